@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// the diffs are the same as diff -u's
+var diffTests = []struct {
+	name     string
+	old, new string
+	diff     string
+}{
+	{
+		name: "same",
+		old:  "a\nb\n",
+		new:  "a\nb\n",
+		diff: "",
+	},
+	{
+		name: "insertion at the start",
+		old:  "b\nc\nd\n",
+		new:  "a\nb\nc\nd\n",
+		diff: "@@ -1,3 +1,4 @@\n+a\n b\n c\n d\n",
+	},
+	{
+		name: "insertion at the end",
+		old:  "a\nb\n",
+		new:  "a\nb\nc\n",
+		diff: "@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+	},
+	{
+		name: "deletion at the start",
+		old:  "a\nb\nc\nd\ne\n",
+		new:  "b\nc\nd\ne\n",
+		diff: "@@ -1,4 +1,3 @@\n-a\n b\n c\n d\n",
+	},
+	{
+		name: "deletion at the end",
+		old:  "a\nb\nc\nd\ne\n",
+		new:  "a\nb\nc\nd\n",
+		diff: "@@ -2,4 +2,3 @@\n b\n c\n d\n-e\n",
+	},
+	{
+		name: "no newline at the end of old",
+		old:  "a\nb",
+		new:  "a\nb\n",
+		diff: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+	},
+	{
+		name: "no newline at the end of new",
+		old:  "a\nb\n",
+		new:  "a\nc",
+		diff: "@@ -1,2 +1,2 @@\n a\n-b\n+c\n\\ No newline at end of file\n",
+	},
+	{
+		name: "empty old",
+		old:  "",
+		new:  "a\nb\n",
+		diff: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+	},
+	{
+		name: "empty new",
+		old:  "a\nb\n",
+		new:  "",
+		diff: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+	},
+	{
+		name: "hunks within twice the context merge",
+		old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+		new:  "1\nX\n3\n4\n5\n6\n7\n8\nY\n10\n11\n12\n",
+		diff: "@@ -1,12 +1,12 @@\n 1\n-2\n+X\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+Y\n 10\n 11\n 12\n",
+	},
+	{
+		name: "hunks further apart don't",
+		old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+		new:  "1\nX\n3\n4\n5\n6\n7\n8\n9\n10\nY\n12\n13\n",
+		diff: "@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n" +
+			"@@ -8,6 +8,6 @@\n 8\n 9\n 10\n-11\n+Y\n 12\n 13\n",
+	},
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, test := range diffTests {
+		want := test.diff
+		if want != "" {
+			want = "--- old\n+++ new\n" + want
+		}
+		if got := unifiedDiff("old", "new", test.old, test.new); got != want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", test.name, got, want)
+		}
+	}
+}
+
+// the edit script turns old into new, changing as few lines as it can
+func TestDiffLines(t *testing.T) {
+	for _, test := range diffTests {
+		a, b := splitLines(test.old), splitLines(test.new)
+		ops := diffLines(a, b)
+		var old, new strings.Builder
+		changes := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				old.WriteString(op.line)
+			}
+			if op.kind != '-' {
+				new.WriteString(op.line)
+			}
+			if op.kind != ' ' {
+				changes++
+			}
+		}
+		if old.String() != test.old || new.String() != test.new {
+			t.Errorf("%s: the edit script gives %q -> %q", test.name, old.String(), new.String())
+		}
+		if want := strings.Count(test.diff, "\n+") + strings.Count(test.diff, "\n-"); changes != want {
+			t.Errorf("%s: %d lines changed, expected %d", test.name, changes, want)
+		}
+	}
+}
@@ -2,16 +2,17 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/tendermint/tendermint/binary"
-	"github.com/tendermint/tendermint/rpc"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type Response struct {
@@ -20,7 +21,7 @@ type Response struct {
 	Error  string
 }
 
-//go:generate go-rpc-gen -interface Client -pkg core -dir core -type *ClientHTTP,*ClientJSON -exclude pipe.go -out-pkg rpc -out client_methods.go
+//go:generate go-rpc-gen -interface Client -pkg core -dir core -type *ClientHTTP,*ClientJSON -local ClientLocal -exclude pipe.go -out-pkg rpc -out client_methods.go -templates templates
 
 type ClientJSON struct {
 	addr string
@@ -36,10 +37,37 @@ func NewClient(addr, typ string) Client {
 		return &ClientHTTP{addr}
 	case "JSONRPC":
 		return &ClientJSON{addr}
+	case "LOCAL":
+		return &ClientLocal{RoundTrip: jsonRoundTrip}
 	}
 	return nil
 }
 
+func (c *ClientJSON) Address() string {
+	return c.addr
+}
+
+func (c *ClientHTTP) Address() string {
+	return c.addr
+}
+
+// the local client has no remote
+func (c *ClientLocal) Address() string {
+	return ""
+}
+
+// copy from into to through the json the server reads and writes,
+// so the local client sees what would go over the wire
+func jsonRoundTrip(from, to interface{}) error {
+	buf, n, err := new(bytes.Buffer), new(int64), new(error)
+	binary.WriteJSON(from, buf, n, err)
+	if *err != nil {
+		return *err
+	}
+	binary.ReadJSON(to, buf.Bytes(), err)
+	return *err
+}
+
 func (c *ClientJSON) Call(method string, args ...interface{}) (*Response, error) {
 	return nil, nil
 }
@@ -49,8 +77,8 @@ func (c *ClientHTTP) Call(method string, args ...interface{}) (*Response, error)
 	if !ok {
 		return nil, fmt.Errorf("No known function method %s", method)
 	}
-	if len(args) != len(fw.args) {
-		return nil, fmt.Errorf("Not enough arguments. Got %d, expected %d for method %s", len(args), len(fw.args), method)
+	if len(args) != len(fw.argNames) {
+		return nil, fmt.Errorf("Not enough arguments. Got %d, expected %d for method %s", len(args), len(fw.argNames), method)
 	}
 	values, err := argsToURLValues(fw.argNames, args...)
 	if err != nil {
@@ -110,13 +138,22 @@ func (c *ClientHTTP) RequestResponse(method string, values url.Values) (*Respons
 	return status, nil
 }
 
-func (c *ClientJSON) requestResponse(s rpc.RPCRequest) ([]byte, error) {
+// the positional params of a jsonrpc request, from the json of each arg
+func jsonParams(args ...string) json.RawMessage {
+	return json.RawMessage("[" + strings.Join(args, ",") + "]")
+}
+
+func (c *ClientJSON) requestResponse(ctx context.Context, s *JSONRPC) ([]byte, error) {
 	b, err := json.Marshal(s)
 	if err != nil {
 		return nil, err
 	}
-	buf := bytes.NewBuffer(b)
-	resp, err := http.Post(c.addr, "text/json", buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.addr, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -137,30 +174,36 @@ type Client interface {
 }
 */
 
-func bytesToString(b []byte) (string, error) {
-	return "0x" + hex.EncodeToString(b), nil
-}
+// then the serialization routines templates apply to each arg by its type, eg. {{wire args}}.
+// each encodes an arg as the json the server will decode it from, as a form value
+// for ClientHTTP, or as a param of the request for ClientJSON.
+// like binary's, they take an *error to set if encoding fails, which the templates
+// pass in ({{wire(args, encErr)}}) and check before making the request
+
+/*rpc-gen:define-set wire
+[]byte bytesToString
+uint   uintToString
+_      jsonToString
+*/
 
-func intToString(b int) (string, error) {
-	return strconv.Itoa(b), nil
+func bytesToString(b []byte, err *error) string {
+	return "\"" + hex.EncodeToString(b) + "\""
 }
 
-func binaryWriter(args ...interface{}) ([]interface{}, error) {
-	list := []interface{}{}
-	for _, a := range args {
-		buf, n, err := new(bytes.Buffer), new(int64), new(error)
-		binary.WriteJSON(a, buf, n, err)
-		if *err != nil {
-			return nil, *err
-		}
-		list = append(list, buf.Bytes())
+func uintToString(i uint, err *error) string {
+	return strconv.FormatUint(uint64(i), 10)
+}
 
+// interface types (eg. types.Tx) must have their concrete types registered with binary
+func jsonToString(v interface{}, err *error) string {
+	if *err != nil {
+		return ""
 	}
-	return list, nil
+	buf, n := new(bytes.Buffer), new(int64)
+	binary.WriteJSON(v, buf, n, err)
+	return buf.String()
 }
 
-// for HTTP, we have a single function that converts args to values
-
 func argsToURLValues(argNames []string, args ...interface{}) (url.Values, error) {
 	values := make(url.Values)
 	if len(argNames) == 0 {
@@ -183,72 +226,3 @@ func argsToURLValues(argNames []string, args ...interface{}) (url.Values, error)
 	}
 	return values, nil
 }
-
-/*rpc-gen:imports:
-github.com/tendermint/tendermint/binary
-github.com/tendermint/tendermint/rpc
-net/http
-io/ioutil
-fmt
-*/
-
-// Template functions to be filled in
-
-/*rpc-gen:template:*ClientJSON func (c *ClientJSON) {{name}}({{args.def}}) ({{response}}) {
-	params, err := binaryWriter({{args.ident}})
-	if err != nil{
-		return nil, err
-	}
-	s := rpc.RPCRequest{
-		JSONRPC: "2.0",
-		Method:  {{lowername}},
-		Params:  params,
-		Id:      0,
-	}
-	body, err := c.requestResponse(s)
-	if err != nil{
-		return nil, err
-	}
-	var status struct {
-		Status string
-		Data   {{response.0}}
-		Error  string
-	}
-	binary.ReadJSON(&status, body, &err)
-	if err != nil {
-		return nil, err
-	}
-	if status.Error != ""{
-		return nil, fmt.Errorf(status.Error)
-	}
-	return status.Data, nil
-}*/
-
-/*rpc-gen:template:*ClientHTTP func (c *ClientHTTP) {{name}}({{args.def}}) ({{response}}){
-	values, err := argsToURLValues({{args.name}}, {{args.ident}})
-	if err != nil{
-		return nil, err
-	}
-	resp, err := http.PostForm(c.addr+{{lowername}}, values)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	var status struct {
-		Status string
-		Data   {{response.0}}
-		Error  string
-	}
-	binary.ReadJSON(&status, body, &err)
-	if err != nil {
-		return nil, err
-	}
-	if status.Error != ""{
-		return nil, fmt.Errorf(status.Error)
-	}
-	return status.Data, nil
-}*/
@@ -1,20 +1,25 @@
-// File generated by github.com/ebuchman/rpc-gen
+// Code generated by "go-rpc-gen -interface Client -pkg core -dir core -type '*ClientHTTP,*ClientJSON' -local ClientLocal -exclude pipe.go -out-pkg rpc -out client_methods.go -templates templates"; DO NOT EDIT.
 
 package rpc
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
 	"github.com/ebuchman/go-rpc-gen/example/core"
 	"github.com/tendermint/tendermint/account"
 	"github.com/tendermint/tendermint/binary"
-	"github.com/tendermint/tendermint/rpc"
 	"github.com/tendermint/tendermint/types"
-	"io/ioutil"
-	"net/http"
 )
 
 type Client interface {
-	BlockchainInfo(minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error)
+	Address() string // returns the remote address
+	BlockchainInfo(ctx context.Context, minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error)
 	BroadcastTx(tx types.Tx) (*core.ResponseBroadcastTx, error)
 	GenPrivAccount() (*core.ResponseGenPrivAccount, error)
 	GetAccount(address []byte) (*core.ResponseGetAccount, error)
@@ -26,12 +31,19 @@ type Client interface {
 	Status() (*core.ResponseStatus, error)
 }
 
-func (c *ClientHTTP) BlockchainInfo(minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error) {
-	values, err := argsToURLValues([]string{"minHeight", "maxHeight"}, minHeight, maxHeight)
+func (c *ClientHTTP) BlockchainInfo(ctx context.Context, minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error) {
+	values, encErr := url.Values{}, new(error)
+	values.Set("min_height", uintToString(minHeight, encErr))
+	values.Set("max_height", uintToString(maxHeight, encErr))
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.addr+"blockchain", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"blockchain_info", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -50,17 +62,23 @@ func (c *ClientHTTP) BlockchainInfo(minHeight uint, maxHeight uint) (*core.Respo
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) BroadcastTx(tx types.Tx) (*core.ResponseBroadcastTx, error) {
-	values, err := argsToURLValues([]string{"tx"}, tx)
+	values, encErr := url.Values{}, new(error)
+	values.Set("tx", jsonToString(tx, encErr))
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"broadcast_tx", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"broadcast_tx", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -79,17 +97,22 @@ func (c *ClientHTTP) BroadcastTx(tx types.Tx) (*core.ResponseBroadcastTx, error)
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) GenPrivAccount() (*core.ResponseGenPrivAccount, error) {
-	values, err := argsToURLValues(nil)
+	values, encErr := url.Values{}, new(error)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"unsafe/gen_priv_account", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"gen_priv_account", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -108,17 +131,23 @@ func (c *ClientHTTP) GenPrivAccount() (*core.ResponseGenPrivAccount, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) GetAccount(address []byte) (*core.ResponseGetAccount, error) {
-	values, err := argsToURLValues([]string{"address"}, address)
+	values, encErr := url.Values{}, new(error)
+	values.Set("address", bytesToString(address, encErr))
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"get_account", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"get_account", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -137,17 +166,23 @@ func (c *ClientHTTP) GetAccount(address []byte) (*core.ResponseGetAccount, error
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) GetBlock(height uint) (*core.ResponseGetBlock, error) {
-	values, err := argsToURLValues([]string{"height"}, height)
+	values, encErr := url.Values{}, new(error)
+	values.Set("height", uintToString(height, encErr))
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"get_block", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"get_block", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -166,17 +201,22 @@ func (c *ClientHTTP) GetBlock(height uint) (*core.ResponseGetBlock, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) ListAccounts() (*core.ResponseListAccounts, error) {
-	values, err := argsToURLValues(nil)
+	values, encErr := url.Values{}, new(error)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"list_accounts", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"list_accounts", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -195,17 +235,22 @@ func (c *ClientHTTP) ListAccounts() (*core.ResponseListAccounts, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) ListValidators() (*core.ResponseListValidators, error) {
-	values, err := argsToURLValues(nil)
+	values, encErr := url.Values{}, new(error)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"list_validators", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"list_validators", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -224,17 +269,22 @@ func (c *ClientHTTP) ListValidators() (*core.ResponseListValidators, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) NetInfo() (*core.ResponseNetInfo, error) {
-	values, err := argsToURLValues(nil)
+	values, encErr := url.Values{}, new(error)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"net_info", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"net_info", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -253,17 +303,24 @@ func (c *ClientHTTP) NetInfo() (*core.ResponseNetInfo, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) SignTx(tx types.Tx, privAccounts []*account.PrivAccount) (*core.ResponseSignTx, error) {
-	values, err := argsToURLValues([]string{"tx", "privAccounts"}, tx, privAccounts)
+	values, encErr := url.Values{}, new(error)
+	values.Set("tx", jsonToString(tx, encErr))
+	values.Set("privAccounts", jsonToString(privAccounts, encErr))
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"unsafe/sign_tx", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"sign_tx", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -282,17 +339,22 @@ func (c *ClientHTTP) SignTx(tx types.Tx, privAccounts []*account.PrivAccount) (*
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
 func (c *ClientHTTP) Status() (*core.ResponseStatus, error) {
-	values, err := argsToURLValues(nil)
+	values, encErr := url.Values{}, new(error)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", c.addr+"status", strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.PostForm(c.addr+"status", values)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -311,307 +373,489 @@ func (c *ClientHTTP) Status() (*core.ResponseStatus, error) {
 		return nil, err
 	}
 	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+		return nil, errors.New(status.Error)
 	}
 	return status.Data, nil
 }
 
-func (c *ClientJSON) BlockchainInfo(minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error) {
-	params, err := binaryWriter(minHeight, maxHeight)
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+func (c *ClientJSON) BlockchainInfo(ctx context.Context, minHeight uint, maxHeight uint) (*core.ResponseBlockchainInfo, error) {
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
-		Method:  "blockchain_info",
-		Params:  params,
-		Id:      0,
+		Method:  "blockchain",
+		Params:  jsonParams(uintToString(minHeight, encErr), uintToString(maxHeight, encErr)),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(ctx, s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseBlockchainInfo
-		Error  string
+	var response struct {
+		Result *core.ResponseBlockchainInfo `json:"result"`
+		Error  *JSONRPCError                `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) BroadcastTx(tx types.Tx) (*core.ResponseBroadcastTx, error) {
-	params, err := binaryWriter(tx)
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "broadcast_tx",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(jsonToString(tx, encErr)),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseBroadcastTx
-		Error  string
+	var response struct {
+		Result *core.ResponseBroadcastTx `json:"result"`
+		Error  *JSONRPCError             `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) GenPrivAccount() (*core.ResponseGenPrivAccount, error) {
-	params, err := binaryWriter()
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
-		Method:  "gen_priv_account",
-		Params:  params,
-		Id:      0,
+		Method:  "unsafe/gen_priv_account",
+		Params:  jsonParams(),
+		Id:      json.RawMessage("0"),
 	}
-	body, err := c.requestResponse(s)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseGenPrivAccount
-		Error  string
+	var response struct {
+		Result *core.ResponseGenPrivAccount `json:"result"`
+		Error  *JSONRPCError                `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) GetAccount(address []byte) (*core.ResponseGetAccount, error) {
-	params, err := binaryWriter(address)
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "get_account",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(bytesToString(address, encErr)),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseGetAccount
-		Error  string
+	var response struct {
+		Result *core.ResponseGetAccount `json:"result"`
+		Error  *JSONRPCError            `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) GetBlock(height uint) (*core.ResponseGetBlock, error) {
-	params, err := binaryWriter(height)
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "get_block",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(uintToString(height, encErr)),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseGetBlock
-		Error  string
+	var response struct {
+		Result *core.ResponseGetBlock `json:"result"`
+		Error  *JSONRPCError          `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) ListAccounts() (*core.ResponseListAccounts, error) {
-	params, err := binaryWriter()
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "list_accounts",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseListAccounts
-		Error  string
+	var response struct {
+		Result *core.ResponseListAccounts `json:"result"`
+		Error  *JSONRPCError              `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) ListValidators() (*core.ResponseListValidators, error) {
-	params, err := binaryWriter()
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "list_validators",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(),
+		Id:      json.RawMessage("0"),
 	}
-	body, err := c.requestResponse(s)
+	if *encErr != nil {
+		return nil, *encErr
+	}
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseListValidators
-		Error  string
+	var response struct {
+		Result *core.ResponseListValidators `json:"result"`
+		Error  *JSONRPCError                `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) NetInfo() (*core.ResponseNetInfo, error) {
-	params, err := binaryWriter()
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "net_info",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseNetInfo
-		Error  string
+	var response struct {
+		Result *core.ResponseNetInfo `json:"result"`
+		Error  *JSONRPCError         `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) SignTx(tx types.Tx, privAccounts []*account.PrivAccount) (*core.ResponseSignTx, error) {
-	params, err := binaryWriter(tx, privAccounts)
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
-		Method:  "sign_tx",
-		Params:  params,
-		Id:      0,
+		Method:  "unsafe/sign_tx",
+		Params:  jsonParams(jsonToString(tx, encErr), jsonToString(privAccounts, encErr)),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseSignTx
-		Error  string
+	var response struct {
+		Result *core.ResponseSignTx `json:"result"`
+		Error  *JSONRPCError        `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
 }
 
 func (c *ClientJSON) Status() (*core.ResponseStatus, error) {
-	params, err := binaryWriter()
-	if err != nil {
-		return nil, err
-	}
-	s := rpc.RPCRequest{
+	encErr := new(error)
+	s := &JSONRPC{
 		JSONRPC: "2.0",
 		Method:  "status",
-		Params:  params,
-		Id:      0,
+		Params:  jsonParams(),
+		Id:      json.RawMessage("0"),
+	}
+	if *encErr != nil {
+		return nil, *encErr
 	}
-	body, err := c.requestResponse(s)
+	body, err := c.requestResponse(context.Background(), s)
 	if err != nil {
 		return nil, err
 	}
-	var status struct {
-		Status string
-		Data   *core.ResponseStatus
-		Error  string
+	var response struct {
+		Result *core.ResponseStatus `json:"result"`
+		Error  *JSONRPCError        `json:"error"`
 	}
-	binary.ReadJSON(&status, body, &err)
+	binary.ReadJSON(&response, body, &err)
 	if err != nil {
 		return nil, err
 	}
-	if status.Error != "" {
-		return nil, fmt.Errorf(status.Error)
+	if response.Error != nil {
+		return nil, response.Error
 	}
-	return status.Data, nil
+	return response.Result, nil
+}
+
+// ClientLocal is a Client that calls the core functions directly, in process.
+// If RoundTrip is set, each arg and result is copied through it, eg. encoded
+// and decoded with the server's codec, so serialization bugs still show up.
+// A method without an error to return panics if RoundTrip fails.
+type ClientLocal struct {
+	RoundTrip func(from, to interface{}) error // decode into to what from encodes to
+}
+
+var _ Client = (*ClientLocal)(nil)
+
+func (_c *ClientLocal) BlockchainInfo(_ctx context.Context, _p0 uint, _p1 uint) (_r0 *core.ResponseBlockchainInfo, _r1 error) {
+	if _c.RoundTrip != nil {
+		var _a0 uint
+		if err := _c.RoundTrip(_p0, &_a0); err != nil {
+			_r1 = err
+			return
+		}
+		_p0 = _a0
+		var _a1 uint
+		if err := _c.RoundTrip(_p1, &_a1); err != nil {
+			_r1 = err
+			return
+		}
+		_p1 = _a1
+	}
+	_r0, _r1 = core.BlockchainInfo(_ctx, _p0, _p1)
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseBlockchainInfo
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) BroadcastTx(_p0 types.Tx) (_r0 *core.ResponseBroadcastTx, _r1 error) {
+	if _c.RoundTrip != nil {
+		var _a0 types.Tx
+		if err := _c.RoundTrip(_p0, &_a0); err != nil {
+			_r1 = err
+			return
+		}
+		_p0 = _a0
+	}
+	_r0, _r1 = core.BroadcastTx(_p0)
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseBroadcastTx
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) GenPrivAccount() (_r0 *core.ResponseGenPrivAccount, _r1 error) {
+	_r0, _r1 = core.GenPrivAccount()
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseGenPrivAccount
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) GetAccount(_p0 []byte) (_r0 *core.ResponseGetAccount, _r1 error) {
+	if _c.RoundTrip != nil {
+		var _a0 []byte
+		if err := _c.RoundTrip(_p0, &_a0); err != nil {
+			_r1 = err
+			return
+		}
+		_p0 = _a0
+	}
+	_r0, _r1 = core.GetAccount(_p0)
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseGetAccount
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) GetBlock(_p0 uint) (_r0 *core.ResponseGetBlock, _r1 error) {
+	if _c.RoundTrip != nil {
+		var _a0 uint
+		if err := _c.RoundTrip(_p0, &_a0); err != nil {
+			_r1 = err
+			return
+		}
+		_p0 = _a0
+	}
+	_r0, _r1 = core.GetBlock(_p0)
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseGetBlock
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) ListAccounts() (_r0 *core.ResponseListAccounts, _r1 error) {
+	_r0, _r1 = core.ListAccounts()
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseListAccounts
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) ListValidators() (_r0 *core.ResponseListValidators, _r1 error) {
+	_r0, _r1 = core.ListValidators()
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseListValidators
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) NetInfo() (_r0 *core.ResponseNetInfo, _r1 error) {
+	_r0, _r1 = core.NetInfo()
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseNetInfo
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) SignTx(_p0 types.Tx, _p1 []*account.PrivAccount) (_r0 *core.ResponseSignTx, _r1 error) {
+	if _c.RoundTrip != nil {
+		var _a0 types.Tx
+		if err := _c.RoundTrip(_p0, &_a0); err != nil {
+			_r1 = err
+			return
+		}
+		_p0 = _a0
+		var _a1 []*account.PrivAccount
+		if err := _c.RoundTrip(_p1, &_a1); err != nil {
+			_r1 = err
+			return
+		}
+		_p1 = _a1
+	}
+	_r0, _r1 = core.SignTx(_p0, _p1)
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseSignTx
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
+}
+
+func (_c *ClientLocal) Status() (_r0 *core.ResponseStatus, _r1 error) {
+	_r0, _r1 = core.Status()
+	if _c.RoundTrip != nil && _r1 == nil {
+		var _v0 *core.ResponseStatus
+		if err := _c.RoundTrip(_r0, &_v0); err != nil {
+			_r1 = err
+			return
+		}
+		_r0 = _v0
+	}
+	return
 }
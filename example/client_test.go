@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ebuchman/go-rpc-gen/example/core"
+)
+
+// stand-ins for the core functions, which need a node to serve them.
+// each checks it got the args the client was called with
+var roundTripFuncs = map[string]*FuncWrapper{
+	"blockchain": funcWrap(func(ctx context.Context, minHeight, maxHeight uint) (*core.ResponseBlockchainInfo, error) {
+		return &core.ResponseBlockchainInfo{LastHeight: minHeight*10 + maxHeight}, nil
+	}, []string{"min_height", "max_height"}),
+	"get_account": funcWrap(func(address []byte) (*core.ResponseGetAccount, error) {
+		return nil, fmt.Errorf("no account %X", address)
+	}, []string{"address"}),
+	"get_block": funcWrap(func(height uint) (*core.ResponseGetBlock, error) {
+		if height != 7 {
+			return nil, fmt.Errorf("expected height 7, got %d", height)
+		}
+		return &core.ResponseGetBlock{}, nil
+	}, []string{"height"}),
+}
+
+// the ClientJSON methods against the jsonrpc handler, over http
+func TestClientJSONRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(toJSONRPCHandler(roundTripFuncs)))
+	defer srv.Close()
+	c := NewClient(srv.URL, "JSONRPC")
+
+	info, err := c.BlockchainInfo(context.Background(), 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LastHeight != 34 {
+		t.Errorf("blockchain: expected 34, got %d", info.LastHeight)
+	}
+
+	if block, err := c.GetBlock(7); err != nil {
+		t.Errorf("get_block: %v", err)
+	} else if block == nil {
+		t.Errorf("get_block: expected a result, got nil")
+	}
+
+	_, err = c.GetAccount([]byte{0xab, 0xcd})
+	jerr, ok := err.(*JSONRPCError)
+	if !ok || jerr.Code != JSONRPC_INTERNAL_ERROR || jerr.Data != "no account ABCD" {
+		t.Errorf("get_account: expected the internal error \"no account ABCD\", got %v", err)
+	}
+}
+
+// a tx that can't be encoded
+type badTx struct {
+	C chan int
+}
+
+func (badTx) WriteSignBytes(w io.Writer, n *int64, err *error) {}
+
+// an arg that can't be encoded is returned as an error, before any request is made
+func TestClientEncodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %s", r.URL)
+	}))
+	defer srv.Close()
+	for _, typ := range []string{"JSONRPC", "HTTP"} {
+		c := NewClient(srv.URL+"/", typ)
+		if _, err := c.BroadcastTx(badTx{}); err == nil {
+			t.Errorf("%s: expected an error encoding the tx", typ)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 //-----------------------------------------------------------------------------
 
+// rpc-gen:unsafe
 func GenPrivAccount() (*ResponseGenPrivAccount, error) {
 	return &ResponseGenPrivAccount{account.GenPrivAccount()}, nil
 }
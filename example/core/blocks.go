@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	. "github.com/tendermint/tendermint/common"
 	"github.com/tendermint/tendermint/types"
@@ -8,7 +9,9 @@ import (
 
 //-----------------------------------------------------------------------------
 
-func BlockchainInfo(minHeight, maxHeight uint) (*ResponseBlockchainInfo, error) {
+// rpc-gen:name blockchain
+// rpc-gen:param minHeight=min_height maxHeight=max_height
+func BlockchainInfo(ctx context.Context, minHeight, maxHeight uint) (*ResponseBlockchainInfo, error) {
 	if maxHeight == 0 {
 		maxHeight = blockStore.Height()
 	} else {
@@ -21,6 +24,9 @@ func BlockchainInfo(minHeight, maxHeight uint) (*ResponseBlockchainInfo, error)
 
 	blockMetas := []*types.BlockMeta{}
 	for height := maxHeight; height >= minHeight; height-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		blockMeta := blockStore.LoadBlockMeta(height)
 		blockMetas = append(blockMetas, blockMeta)
 	}
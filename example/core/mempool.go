@@ -2,7 +2,6 @@ package core
 
 import (
 	"fmt"
-	. "github.com/tendermint/tendermint/common"
 	"github.com/tendermint/tendermint/merkle"
 	"github.com/tendermint/tendermint/state"
 	"github.com/tendermint/tendermint/types"
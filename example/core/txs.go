@@ -8,6 +8,7 @@ import (
 
 //-----------------------------------------------------------------------------
 
+// rpc-gen:unsafe
 func SignTx(tx types.Tx, privAccounts []*account.PrivAccount) (*ResponseSignTx, error) {
 	// more checks?
 
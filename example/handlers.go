@@ -5,62 +5,46 @@ TODO: support Call && GetStorage.
 */
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/tendermint/tendermint/binary"
-	"github.com/tendermint/tendermint/rpc/core"
 	"io/ioutil"
 	"net/http"
 	"reflect"
 )
 
-// cache all type information about each function up front
-// (func, responseStruct, argNames)
-var funcMap = map[string]*FuncWrapper{
-	"status":                  funcWrap(core.Status, []string{}),
-	"net_info":                funcWrap(core.NetInfo, []string{}),
-	"blockchain":              funcWrap(core.BlockchainInfo, []string{"min_height", "max_height"}),
-	"get_block":               funcWrap(core.GetBlock, []string{"height"}),
-	"get_account":             funcWrap(core.GetAccount, []string{"address"}),
-	"get_storage":             funcWrap(core.GetStorage, []string{"address", "storage"}),
-	"call":                    funcWrap(core.Call, []string{"address", "data"}),
-	"list_validators":         funcWrap(core.ListValidators, []string{}),
-	"dump_storage":            funcWrap(core.DumpStorage, []string{"address"}),
-	"broadcast_tx":            funcWrap(core.BroadcastTx, []string{"tx"}),
-	"list_accounts":           funcWrap(core.ListAccounts, []string{}),
-	"unsafe/gen_priv_account": funcWrap(core.GenPrivAccount, []string{}),
-	"unsafe/sign_tx":          funcWrap(core.SignTx, []string{"tx", "privAccounts"}),
-}
-
-func initHandlers() {
-	// HTTP endpoints
-	for funcName, funcInfo := range funcMap {
-		http.HandleFunc("/"+funcName, toHttpHandler(funcInfo))
-	}
-
-	// JSONRPC endpoints
-	http.HandleFunc("/", JSONRPCHandler)
-}
+//go:generate go-rpc-gen -server -typed -pkg core -dir core -exclude pipe.go -out-pkg rpc -out server_methods.go
 
 //-------------------------------------
 
 // holds all type information for each function
 type FuncWrapper struct {
 	f        reflect.Value  // function from "rpc/core"
-	args     []reflect.Type // type of each function arg
+	ctx      bool           // whether the function takes a context first
+	args     []reflect.Type // type of each function arg (excluding the context)
 	returns  []reflect.Type // type of each return arg
 	argNames []string       // name of each argument
 }
 
 func funcWrap(f interface{}, args []string) *FuncWrapper {
+	argTypes := funcArgTypes(f)
+	ctx := len(argTypes) > 0 && argTypes[0] == contextType
+	if ctx {
+		argTypes = argTypes[1:]
+	}
 	return &FuncWrapper{
 		f:        reflect.ValueOf(f),
-		args:     funcArgTypes(f),
+		ctx:      ctx,
+		args:     argTypes,
 		returns:  funcReturnTypes(f),
 		argNames: args,
 	}
 }
 
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 func funcArgTypes(f interface{}) []reflect.Type {
 	t := reflect.TypeOf(f)
 	n := t.NumIn()
@@ -71,6 +55,27 @@ func funcArgTypes(f interface{}) []reflect.Type {
 	return types
 }
 
+// call the function, passing the request's context if it takes one
+func (funcInfo *FuncWrapper) call(ctx context.Context, args []reflect.Value) []reflect.Value {
+	if funcInfo.ctx {
+		args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+	}
+	return funcInfo.f.Call(args)
+}
+
+// decode the args with param, and call the function by reflection
+func (funcInfo *FuncWrapper) callWithParams(ctx context.Context, param decodeParam) (interface{}, error) {
+	args := make([]reflect.Value, len(funcInfo.args))
+	for i, ty := range funcInfo.args {
+		v := reflect.New(ty)
+		if err := param(i, funcInfo.argNames[i], v.Interface()); err != nil {
+			return nil, err
+		}
+		args[i] = v.Elem()
+	}
+	return returnsToResponse(funcInfo.call(ctx, args))
+}
+
 func funcReturnTypes(f interface{}) []reflect.Type {
 	t := reflect.TypeOf(f)
 	n := t.NumOut()
@@ -84,60 +89,257 @@ func funcReturnTypes(f interface{}) []reflect.Type {
 //-----------------------------------------------------------------------------
 // rpc.json
 
+// a jsonrpc 2.0 request. Params are positional (an array) or named (an object),
+// and the Id is left out of a notification, which gets no response
 type JSONRPC struct {
-	JSONRPC string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-	Id      int           `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Id      json.RawMessage `json:"id,omitempty"`
+}
+
+// a jsonrpc 2.0 response, with either the result or the error.
+// the Id is the request's, or null if it couldn't be read
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	Id      json.RawMessage `json:"id"`
+}
+
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	if e.Data == "" {
+		return e.Message
+	}
+	return e.Message + ": " + e.Data
+}
+
+// the standard error codes
+const (
+	JSONRPC_PARSE_ERROR      = -32700
+	JSONRPC_INVALID_REQUEST  = -32600
+	JSONRPC_METHOD_NOT_FOUND = -32601
+	JSONRPC_INVALID_PARAMS   = -32602
+	JSONRPC_INTERNAL_ERROR   = -32603
+)
+
+var jsonrpcMessages = map[int]string{
+	JSONRPC_PARSE_ERROR:      "Parse error",
+	JSONRPC_INVALID_REQUEST:  "Invalid Request",
+	JSONRPC_METHOD_NOT_FOUND: "Method not found",
+	JSONRPC_INVALID_PARAMS:   "Invalid params",
+	JSONRPC_INTERNAL_ERROR:   "Internal error",
+}
+
+var jsonNull = json.RawMessage("null")
+
+func jsonrpcErrorResponse(id json.RawMessage, code int, data string) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error:   &JSONRPCError{code, jsonrpcMessages[code], data},
+		Id:      id,
+	}
 }
 
 // jsonrpc calls grab the given method's function info and runs reflect.Call
 func JSONRPCHandler(w http.ResponseWriter, r *http.Request) {
-	b, _ := ioutil.ReadAll(r.Body)
-	var jrpc JSONRPC
-	err := json.Unmarshal(b, &jrpc)
+	toJSONRPCHandler(funcMap)(w, r)
+}
+
+// convert from a route table to the jsonrpc handler
+func toJSONRPCHandler(funcMap map[string]*FuncWrapper) func(http.ResponseWriter, *http.Request) {
+	return jsonrpcHandler(func(method string) (typedFunc, bool) {
+		funcInfo, ok := funcMap[method]
+		if !ok {
+			return nil, false
+		}
+		return funcInfo.callWithParams, true
+	})
+}
+
+// serve a request, or a batch of them, calling the function lookup finds for each method.
+// a request, or a batch, of notifications only gets 204 No Content
+func jsonrpcHandler(lookup func(method string) (typedFunc, bool)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeJSONRPCResponse(w, jsonrpcErrorResponse(jsonNull, JSONRPC_PARSE_ERROR, err.Error()))
+			return
+		}
+		b = bytes.TrimSpace(b)
+		if !json.Valid(b) {
+			writeJSONRPCResponse(w, jsonrpcErrorResponse(jsonNull, JSONRPC_PARSE_ERROR, "invalid json"))
+			return
+		}
+		if b[0] != '[' {
+			if res := serveJSONRPC(r.Context(), lookup, b); res != nil {
+				writeJSONRPCResponse(w, res)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var batch []json.RawMessage
+		json.Unmarshal(b, &batch) // valid json, so it's an array
+		if len(batch) == 0 {
+			writeJSONRPCResponse(w, jsonrpcErrorResponse(jsonNull, JSONRPC_INVALID_REQUEST, "empty batch"))
+			return
+		}
+		responses := []*JSONRPCResponse{}
+		for _, req := range batch {
+			if res := serveJSONRPC(r.Context(), lookup, req); res != nil {
+				responses = append(responses, res)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSONRPCResponse(w, responses)
+	}
+}
+
+// serve a single request, returning nil for a notification
+func serveJSONRPC(ctx context.Context, lookup func(method string) (typedFunc, bool), b json.RawMessage) *JSONRPCResponse {
+	jrpc, err := readJSONRPC(b)
 	if err != nil {
-		// TODO
+		return jsonrpcErrorResponse(jsonNull, JSONRPC_INVALID_REQUEST, err.Error())
+	}
+	res := callJSONRPC(ctx, lookup, jrpc)
+	if jrpc.Id == nil {
+		return nil
+	}
+	res.Id = jrpc.Id
+	return res
+}
+
+// check the request is a jsonrpc 2.0 request object
+func readJSONRPC(b json.RawMessage) (*JSONRPC, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("request must be an object")
+	}
+	jrpc := new(JSONRPC)
+	if err := json.Unmarshal(fields["jsonrpc"], &jrpc.JSONRPC); err != nil || jrpc.JSONRPC != "2.0" {
+		return nil, fmt.Errorf("jsonrpc must be \"2.0\"")
 	}
+	// null would unmarshal to "", but a method name has to be there
+	if method := fields["method"]; string(method) == "null" || json.Unmarshal(method, &jrpc.Method) != nil {
+		return nil, fmt.Errorf("method must be a string")
+	}
+	if params, ok := fields["params"]; ok {
+		if params[0] != '[' && params[0] != '{' {
+			return nil, fmt.Errorf("params must be an array or an object")
+		}
+		jrpc.Params = params
+	}
+	if id, ok := fields["id"]; ok {
+		if id[0] == '{' || id[0] == '[' || id[0] == 't' || id[0] == 'f' {
+			return nil, fmt.Errorf("id must be a string, a number or null")
+		}
+		jrpc.Id = id
+	}
+	return jrpc, nil
+}
 
-	funcInfo := funcMap[jrpc.Method]
-	args, err := jsonParamsToArgs(funcInfo, jrpc.Params)
+// call the method with the request's params, recovering from panics
+func callJSONRPC(ctx context.Context, lookup func(method string) (typedFunc, bool), jrpc *JSONRPC) (res *JSONRPCResponse) {
+	f, ok := lookup(jrpc.Method)
+	if !ok {
+		return jsonrpcErrorResponse(nil, JSONRPC_METHOD_NOT_FOUND, jrpc.Method)
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			res = jsonrpcErrorResponse(nil, JSONRPC_INTERNAL_ERROR, fmt.Sprintf("%v", e))
+		}
+	}()
+	response, err := f(ctx, jsonrpcParams(jrpc.Params))
+	if _, ok := err.(paramError); ok {
+		return jsonrpcErrorResponse(nil, JSONRPC_INVALID_PARAMS, err.Error())
+	}
 	if err != nil {
-		WriteAPIResponse(w, API_INVALID_PARAM, nil, err.Error())
-		return
+		return jsonrpcErrorResponse(nil, JSONRPC_INTERNAL_ERROR, err.Error())
+	}
+	if response == nil {
+		// a successful call always has a result
+		return &JSONRPCResponse{JSONRPC: "2.0", Result: jsonNull}
 	}
-	returns := funcInfo.f.Call(args)
-	response, err := returnsToResponse(returns)
+	buf, n := new(bytes.Buffer), new(int64)
+	binary.WriteJSON(response, buf, n, &err)
 	if err != nil {
-		WriteAPIResponse(w, API_ERROR, nil, err.Error())
-		return
+		return jsonrpcErrorResponse(nil, JSONRPC_INTERNAL_ERROR, err.Error())
 	}
-	WriteAPIResponse(w, API_OK, response, "")
+	return &JSONRPCResponse{JSONRPC: "2.0", Result: buf.Bytes()}
 }
 
-// covert a list of interfaces to properly typed values
-func jsonParamsToArgs(funcInfo *FuncWrapper, params []interface{}) ([]reflect.Value, error) {
-	values := make([]reflect.Value, len(params))
-	for i, p := range params {
-		ty := funcInfo.args[i]
-		v, err := _jsonObjectToArg(ty, p)
+// decode params by position from an array, or by name from an object
+func jsonrpcParams(params json.RawMessage) decodeParam {
+	var list []interface{}
+	var named map[string]interface{}
+	// numbers are kept as json.Numbers, so a uint64 above 2^53 isn't
+	// rounded on its way through a float64
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	if len(params) > 0 && params[0] == '[' {
+		dec.Decode(&list)
+	} else {
+		dec.Decode(&named)
+	}
+	return func(i int, name string, v interface{}) error {
+		var object interface{}
+		var ok bool
+		if named != nil {
+			object, ok = named[name]
+		} else if i < len(list) {
+			object, ok = list[i], true
+		}
+		if !ok {
+			return paramError{fmt.Errorf("missing param %s", name)}
+		}
+		var err error
+		binary.ReadJSONFromObject(v, object, &err)
 		if err != nil {
-			return nil, err
+			return paramError{err}
 		}
-		values[i] = v
+		return nil
 	}
-	return values, nil
 }
 
-func _jsonObjectToArg(ty reflect.Type, object interface{}) (reflect.Value, error) {
-	var err error
-	v := reflect.New(ty)
-	binary.ReadJSONFromObject(v.Interface(), object, &err)
-	if err != nil {
-		return v, err
+// write the response, or a batch of them
+func writeJSONRPCResponse(w http.ResponseWriter, res interface{}) {
+	var b []byte
+	switch res := res.(type) {
+	case *JSONRPCResponse:
+		b = marshalJSONRPCResponse(res)
+	case []*JSONRPCResponse:
+		batch := make([]json.RawMessage, len(res))
+		for i, r := range res {
+			batch[i] = marshalJSONRPCResponse(r)
+		}
+		b, _ = json.Marshal(batch) // each is valid json
 	}
-	v = v.Elem()
-	return v, nil
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(b)
+}
+
+// a response that can't be marshalled, eg. with a result that isn't valid json,
+// is replaced with an internal error for the same id
+func marshalJSONRPCResponse(res *JSONRPCResponse) []byte {
+	b, err := json.Marshal(res)
+	if err == nil {
+		return b
+	}
+	log.Warn("Failed to write JSONRPCResponse", "error", err)
+	b, _ = json.Marshal(jsonrpcErrorResponse(res.Id, JSONRPC_INTERNAL_ERROR, err.Error()))
+	return b
 }
 
 // rpc.json
@@ -152,7 +354,7 @@ func toHttpHandler(funcInfo *FuncWrapper) func(http.ResponseWriter, *http.Reques
 			WriteAPIResponse(w, API_INVALID_PARAM, nil, err.Error())
 			return
 		}
-		returns := funcInfo.f.Call(args)
+		returns := funcInfo.call(r.Context(), args)
 		response, err := returnsToResponse(returns)
 		if err != nil {
 			WriteAPIResponse(w, API_ERROR, nil, err.Error())
@@ -203,3 +405,62 @@ func returnsToResponse(returns []reflect.Value) (interface{}, error) {
 	}
 	return returns[0].Interface(), nil
 }
+
+//-----------------------------------------------------------------------------
+// rpc.typed
+
+// decode the arg at index i of a call, named name, into v (a pointer to the arg)
+type decodeParam func(i int, name string, v interface{}) error
+
+// a core function, generated with -typed, which decodes its args with param
+// into their concrete types and calls the function directly, without reflection
+type typedFunc func(ctx context.Context, param decodeParam) (interface{}, error)
+
+// an arg that couldn't be decoded, as opposed to an error from the function
+type paramError struct {
+	err error
+}
+
+func (e paramError) Error() string {
+	return e.err.Error()
+}
+
+// convert from a typed function to the http handler
+func toTypedHttpHandler(f typedFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := func(i int, name string, v interface{}) error {
+			var err error
+			binary.ReadJSON(v, []byte(GetParam(r, name)), &err)
+			if err != nil {
+				return paramError{err}
+			}
+			return nil
+		}
+		response, err := f(r.Context(), param)
+		writeTypedResponse(w, response, err)
+	}
+}
+
+// convert from a table of typed functions to the jsonrpc handler
+func toTypedJSONRPCHandler(funcMap map[string]typedFunc) func(http.ResponseWriter, *http.Request) {
+	return jsonrpcHandler(func(method string) (typedFunc, bool) {
+		f, ok := funcMap[method]
+		return f, ok
+	})
+}
+
+// write the result of a typed function, or its error
+func writeTypedResponse(w http.ResponseWriter, response interface{}, err error) {
+	if _, ok := err.(paramError); ok {
+		WriteAPIResponse(w, API_INVALID_PARAM, nil, err.Error())
+		return
+	}
+	if err != nil {
+		WriteAPIResponse(w, API_ERROR, nil, err.Error())
+		return
+	}
+	WriteAPIResponse(w, API_OK, response, "")
+}
+
+// rpc.typed
+//-----------------------------------------------------------------------------
@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// core.GetBlock refuses height 0, which is enough to get each error
+var jsonrpcTests = []struct {
+	request  string
+	response string // "" for no response
+}{
+	{`{"jsonrpc":"2.0","method"`,
+		`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error","data":"invalid json"},"id":null}`},
+	{`{"jsonrpc":"1.0","method":"get_block","params":[0],"id":1}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"jsonrpc must be \"2.0\""},"id":null}`},
+	{`{"jsonrpc":"2.0","method":1,"params":"bar"}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"method must be a string"},"id":null}`},
+	{`{"jsonrpc":"2.0","method":null,"id":1}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"method must be a string"},"id":null}`},
+	{`{"jsonrpc":"2.0","method":"get_block","params":0,"id":1}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"params must be an array or an object"},"id":null}`},
+	{`{"jsonrpc":"2.0","method":"get_block","params":[0],"id":{}}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"id must be a string, a number or null"},"id":null}`},
+	{`{"jsonrpc":"2.0","method":"nope","id":"a"}`,
+		`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":"nope"},"id":"a"}`},
+	{`{"jsonrpc":"2.0","method":"get_block","id":2}`,
+		`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"missing param height"},"id":2}`},
+	{`{"jsonrpc":"2.0","method":"get_block","params":{"hieght":1},"id":3}`,
+		`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"missing param height"},"id":3}`},
+	{`{"jsonrpc":"2.0","method":"get_block","params":[0],"id":4}`,
+		`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"height must be greater than 1"},"id":4}`},
+	{`{"jsonrpc":"2.0","method":"get_block","params":{"height":0},"id":null}`,
+		`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"height must be greater than 1"},"id":null}`},
+	// notifications
+	{`{"jsonrpc":"2.0","method":"get_block","params":[0]}`, ``},
+	{`{"jsonrpc":"2.0","method":"nope"}`, ``},
+	// batches
+	{`[]`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"empty batch"},"id":null}`},
+	{`[{"jsonrpc":"2.0","method":"nope","id":1}, {"jsonrpc":"2.0","method":"nope"}, 1]`,
+		`[{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":"nope"},"id":1},` +
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"request must be an object"},"id":null}]`},
+	{`[{"jsonrpc":"2.0","method":"nope"}]`, ``},
+}
+
+func TestJSONRPCHandler(t *testing.T) {
+	testJSONRPCHandler(t, JSONRPCHandler)
+}
+
+func TestTypedJSONRPCHandler(t *testing.T) {
+	testJSONRPCHandler(t, toTypedJSONRPCHandler(typedFuncMap))
+}
+
+func testJSONRPCHandler(t *testing.T, handler http.HandlerFunc) {
+	for _, test := range jsonrpcTests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(test.request)))
+		if test.response == "" {
+			if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+				t.Errorf("%s: expected no response, got %d %s", test.request, w.Code, w.Body)
+			}
+			continue
+		}
+		if got := w.Body.String(); got != test.response {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.request, got, test.response)
+		}
+	}
+}
+
+// a uint64 above 2^53 isn't rounded, by position or by name
+func TestJSONRPCParamsPrecision(t *testing.T) {
+	const n = 1<<53 + 1
+	for _, params := range []string{`[9007199254740993]`, `{"n":9007199254740993}`} {
+		var got uint64
+		if err := jsonrpcParams(json.RawMessage(params))(0, "n", &got); err != nil {
+			t.Errorf("%s: %v", params, err)
+		} else if got != n {
+			t.Errorf("%s: got %d", params, got)
+		}
+	}
+}
+
+// a response that can't be marshalled is an internal error, alone or in a batch
+func TestWriteJSONRPCResponseMarshalError(t *testing.T) {
+	bad := &JSONRPCResponse{JSONRPC: "2.0", Result: json.RawMessage("{"), Id: json.RawMessage("1")}
+	good := &JSONRPCResponse{JSONRPC: "2.0", Result: jsonNull, Id: json.RawMessage("2")}
+	for _, res := range []interface{}{bad, []*JSONRPCResponse{bad, good}} {
+		w := httptest.NewRecorder()
+		writeJSONRPCResponse(w, res)
+		var got []JSONRPCResponse
+		b := w.Body.Bytes()
+		if _, ok := res.(*JSONRPCResponse); ok {
+			b = append(append([]byte("["), b...), ']')
+		}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%v: %s", err, w.Body)
+		}
+		if got[0].Error == nil || got[0].Error.Code != JSONRPC_INTERNAL_ERROR || string(got[0].Id) != "1" {
+			t.Errorf("expected an internal error for id 1, got %s", w.Body)
+		}
+		if len(got) > 1 && (got[1].Error != nil || string(got[1].Id) != "2") {
+			t.Errorf("expected the result for id 2, got %s", w.Body)
+		}
+	}
+}
+
+// the reflective handlers (toHttpHandler, JSONRPCHandler) against the typed ones
+// generated with -typed, serving get_block. height 0 is refused by core.GetBlock,
+// so only the decoding, the dispatch and the response are measured
+
+func BenchmarkHTTPHandler(b *testing.B) {
+	r := httptest.NewRequest("GET", "/get_block?height=0", nil)
+	b.Run("reflect", func(b *testing.B) {
+		benchmarkHandler(b, toHttpHandler(funcMap["get_block"]), r, nil)
+	})
+	b.Run("typed", func(b *testing.B) {
+		benchmarkHandler(b, toTypedHttpHandler(typedFuncMap["get_block"]), r, nil)
+	})
+}
+
+func BenchmarkJSONRPCHandler(b *testing.B) {
+	body := []byte(`{"jsonrpc":"2.0","method":"get_block","params":[0],"id":0}`)
+	r := httptest.NewRequest("POST", "/", nil)
+	b.Run("reflect", func(b *testing.B) {
+		benchmarkHandler(b, JSONRPCHandler, r, body)
+	})
+	b.Run("typed", func(b *testing.B) {
+		benchmarkHandler(b, toTypedJSONRPCHandler(typedFuncMap), r, body)
+	})
+}
+
+// serve r b.N times, with the body if it has one
+func benchmarkHandler(b *testing.B, handler http.HandlerFunc, r *http.Request, body []byte) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		handler(httptest.NewRecorder(), r)
+	}
+}
@@ -0,0 +1,119 @@
+// Code generated by "go-rpc-gen -server -typed -pkg core -dir core -exclude pipe.go -out-pkg rpc -out server_methods.go"; DO NOT EDIT.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ebuchman/go-rpc-gen/example/core"
+	"github.com/tendermint/tendermint/account"
+	"github.com/tendermint/tendermint/types"
+)
+
+// cache all type information about each function up front
+// (func, responseStruct, argNames)
+var funcMap = map[string]*FuncWrapper{
+	"blockchain":              funcWrap(core.BlockchainInfo, []string{"min_height", "max_height"}),
+	"broadcast_tx":            funcWrap(core.BroadcastTx, []string{"tx"}),
+	"unsafe/gen_priv_account": funcWrap(core.GenPrivAccount, []string{}),
+	"get_account":             funcWrap(core.GetAccount, []string{"address"}),
+	"get_block":               funcWrap(core.GetBlock, []string{"height"}),
+	"list_accounts":           funcWrap(core.ListAccounts, []string{}),
+	"list_validators":         funcWrap(core.ListValidators, []string{}),
+	"net_info":                funcWrap(core.NetInfo, []string{}),
+	"unsafe/sign_tx":          funcWrap(core.SignTx, []string{"tx", "privAccounts"}),
+	"status":                  funcWrap(core.Status, []string{}),
+}
+
+// call each function with its args decoded by a decodeParam
+var typedFuncMap = map[string]typedFunc{
+	"blockchain":              callBlockchainInfo,
+	"broadcast_tx":            callBroadcastTx,
+	"unsafe/gen_priv_account": callGenPrivAccount,
+	"get_account":             callGetAccount,
+	"get_block":               callGetBlock,
+	"list_accounts":           callListAccounts,
+	"list_validators":         callListValidators,
+	"net_info":                callNetInfo,
+	"unsafe/sign_tx":          callSignTx,
+	"status":                  callStatus,
+}
+
+func callBlockchainInfo(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	var _p0 uint
+	if err := _param(0, "min_height", &_p0); err != nil {
+		return nil, err
+	}
+	var _p1 uint
+	if err := _param(1, "max_height", &_p1); err != nil {
+		return nil, err
+	}
+	return core.BlockchainInfo(_ctx, _p0, _p1)
+}
+
+func callBroadcastTx(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	var _p0 types.Tx
+	if err := _param(0, "tx", &_p0); err != nil {
+		return nil, err
+	}
+	return core.BroadcastTx(_p0)
+}
+
+func callGenPrivAccount(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	return core.GenPrivAccount()
+}
+
+func callGetAccount(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	var _p0 []byte
+	if err := _param(0, "address", &_p0); err != nil {
+		return nil, err
+	}
+	return core.GetAccount(_p0)
+}
+
+func callGetBlock(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	var _p0 uint
+	if err := _param(0, "height", &_p0); err != nil {
+		return nil, err
+	}
+	return core.GetBlock(_p0)
+}
+
+func callListAccounts(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	return core.ListAccounts()
+}
+
+func callListValidators(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	return core.ListValidators()
+}
+
+func callNetInfo(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	return core.NetInfo()
+}
+
+func callSignTx(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	var _p0 types.Tx
+	if err := _param(0, "tx", &_p0); err != nil {
+		return nil, err
+	}
+	var _p1 []*account.PrivAccount
+	if err := _param(1, "privAccounts", &_p1); err != nil {
+		return nil, err
+	}
+	return core.SignTx(_p0, _p1)
+}
+
+func callStatus(_ctx context.Context, _param decodeParam) (interface{}, error) {
+	return core.Status()
+}
+
+func initHandlers() {
+	// HTTP endpoints
+	for funcName, f := range typedFuncMap {
+		http.HandleFunc("/"+funcName, toTypedHttpHandler(f))
+	}
+
+	// JSONRPC endpoints
+	http.HandleFunc("/", toTypedJSONRPCHandler(typedFuncMap))
+}
@@ -1,20 +1,16 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
-	gofmt "go/format"
-	goparser "go/parser"
-	gotoken "go/token"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
+
+	"github.com/ebuchman/go-rpc-gen/rpcgen"
 )
 
 var (
-	GoPath = os.Getenv("GOPATH")
-
 	interfaceF = flag.String("interface", "", "interface type to define the rpc methods on")
 	typeF      = flag.String("type", "", "comma separated list of types that should implement the interface")
 	pkgNameF   = flag.String("pkg", "", "package containing functions providing the core functionality for the rpc")
@@ -22,108 +18,133 @@ var (
 	outF       = flag.String("out", "client_methods.go", "output file for client methods")
 	outPkgF    = flag.String("out-pkg", "", "name of the package for which code is to be generated")
 	excludeF   = flag.String("exclude", "", "comma separated list of files to exclude public functions from (relative to pkg)")
-	//templatesF = flag.String("templates", ".", "file/s in which the template functions are located")
+	serviceF   = flag.String("service", "", "receiver type whose exported methods are used instead of the package's functions (eg. *core.Service)")
+	localF     = flag.String("local", "", "name of a client to generate that calls the core functions directly, in process (eg. ClientLocal)")
+	mockF      = flag.String("mock", "", "name of a mock implementation of the interface to generate along with the clients (eg. MockClient)")
+	serverF    = flag.Bool("server", false, "generate the server-side handler table instead of the client methods")
+	typedF     = flag.Bool("typed", false, "with -server, generate typed handlers which call the core functions without reflection, and serve them")
+	checkF     = flag.Bool("check", false, "check the -out file is up to date instead of writing it: print a diff and exit 1 if it isn't")
+	templatesF = flag.String("templates", "", "comma separated list of template files, or directories of .tmpl files (in addition to templates in comments)")
 )
 
+// generate for the package in the current dir, see rpcgen.Config
 func main() {
 
 	flag.Parse()
 
-	iface := *interfaceF
-	types := strings.Split(*typeF, ",")
-	pkgName := *pkgNameF
-	dir := *dirF
-	outFile := *outF
-	excludes := strings.Split(*excludeF, ",")
-	outPkg := *outPkgF
-	//	templateFiles := strings.Split(*templatesF, ",")
-
-	fset := gotoken.NewFileSet() // positions are relative to fset
-
-	// get the core functions to be exposed
-	corePkgs, err := goparser.ParseDir(fset, dir, returnFilter(excludes), 0)
-	if err != nil {
-		panic(err)
+	cfg := rpcgen.Config{
+		Dir:       ".",
+		OutPkg:    *outPkgF,
+		OutFile:   *outF,
+		Interface: *interfaceF,
+		Types:     splitList(*typeF),
+		Templates: splitList(*templatesF),
+		CoreDir:   *dirF,
+		CorePkg:   *pkgNameF,
+		Excludes:  splitList(*excludeF),
+		Service:   *serviceF,
+		Local:     *localF,
+		Mock:      *mockF,
+		Server:    *serverF,
+		Typed:     *typedF,
+		Command:   commandLine(),
 	}
-	corePkg := onePkg(corePkgs)
-	coreFuncs := getFuncs(corePkg)
-	corePkgImportPath, err := goImportPathFromDir(dir)
+	src, err := rpcgen.Generate(cfg)
 	if err != nil {
-		panic(err)
+		fatal(err)
 	}
 
-	// get the interface to be populated (present in current dir)
-	pkgs, err := goparser.ParseDir(fset, ".", nil, goparser.ParseComments)
-	if err != nil {
-		panic(err)
+	// nothing is written unless all of it was generated
+	if *checkF {
+		err = checkGoFile(*outF, src)
+	} else {
+		err = writeFileAtomic(*outF, src)
 	}
-	pkg := onePkg(pkgs)
-
-	interfaceDef := fmt.Sprintf(`
-type %s interface{
-
-}`, iface)
-
-	// init the rpc generator by parsing the templates and definitions
-	rpcGen, err := initRpcGen(pkg)
 	if err != nil {
-		panic(err)
-	}
-	if len(rpcGen.templates) != len(types) {
-		panic(fmt.Sprintf("rpc-gen requires equal numbers of types and templates. Got %d, %d", len(types), len(rpcGen.templates)))
+		fatal(err)
 	}
+}
 
-	imports := getImports(corePkg, corePkgImportPath)
-	// populate interface and stringify func defs
-	stringFuncs, interfaceDef, neededImports := populateInterface(interfaceDef, coreFuncs, imports, pkgName, corePkgImportPath)
-
-	// add base imports to neededImports
-	for k, v := range rpcGen.imports {
-		neededImports[k] = v
+// a comma separated list, eg. for -type
+func splitList(s string) []string {
+	if s == "" {
+		return nil
 	}
-	buf := new(bytes.Buffer)
-	fmt.Fprintln(buf, "// File generated by github.com/ebuchman/rpc-gen")
-	fmt.Fprintln(buf, "")
-	fmt.Fprintln(buf, "package", outPkg)
-	fmt.Fprintln(buf, "")
-	fmt.Fprintln(buf, "import(")
-	for n, im := range neededImports {
-		if n != path.Base(im) {
-			fmt.Fprintln(buf, "\t"+n+" \""+im+"\"")
-		} else {
-			fmt.Fprintln(buf, "\t\""+im+"\"")
+	return strings.Split(s, ",")
+}
 
+// the command line rpc-gen was run with, quoted for the shell.
+// the program is always go-rpc-gen, however it was invoked,
+// so the output is the same with go run or an installed binary
+func commandLine() string {
+	args := []string{"go-rpc-gen"}
+	for _, a := range os.Args[1:] {
+		// -check doesn't change the output
+		if a == "-check" || a == "--check" || strings.HasPrefix(a, "-check=") || strings.HasPrefix(a, "--check=") {
+			continue
 		}
+		args = append(args, shellQuote(a))
 	}
-	fmt.Fprintln(buf, ")")
-	fmt.Fprintln(buf, "")
-	fmt.Fprintf(buf, interfaceDef)
-
-	fmt.Println(string(buf.Bytes()))
+	return strings.Join(args, " ")
+}
 
-	// for each client type, implement the interface
-	// using its template and the stringFuncs
-	for _, clientType := range types {
-		implementation, err := rpcGen.implementInterface(clientType, stringFuncs)
-		if err != nil {
-			panic(err)
+// quote the arg if the shell would do anything with it
+func shellQuote(arg string) string {
+	safe := arg != ""
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./,=:@+%", r)) {
+			safe = false
+			break
 		}
-		// write implementation to buffer
-		buf.Write(implementation)
 	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
 
-	// parse the generated source text for the sake of gofmt
-	data := buf.Bytes()
-	node, err := goparser.ParseFile(fset, "", data, goparser.ParseComments)
-	if err != nil {
-		panic(err)
+// report the error, compiler style, and exit
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// print a diff from the file to the generated source, and
+// return an error if there is one (or the file doesn't exist)
+func checkGoFile(outFile string, src []byte) error {
+	old, err := os.ReadFile(outFile)
+	oldName := outFile
+	if os.IsNotExist(err) {
+		oldName = "/dev/null"
+	} else if err != nil {
+		return err
+	}
+	diff := unifiedDiff(oldName, outFile+" (generated)", string(old), string(src))
+	if diff == "" {
+		return nil
 	}
+	fmt.Print(diff)
+	return fmt.Errorf("%s is out of date, rerun go generate", outFile)
+}
 
-	// gofmt and write to file
-	f, err := os.Create(outFile)
+// write the file by renaming a temporary one over it,
+// so it's never left half written
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
 	if err != nil {
-		panic(err)
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
 	}
-	defer f.Close()
-	gofmt.Node(f, fset, node)
+	return os.Rename(f.Name(), filename)
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// with $GO_RPC_GEN_MAIN set, the test binary is go-rpc-gen
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_RPC_GEN_MAIN") != "" {
+		os.Args = append([]string{"go-rpc-gen"}, strings.Fields(os.Getenv("GO_RPC_GEN_MAIN"))...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// run go-rpc-gen in dir, returning its stdout and whether it exited zero
+func runMain(t *testing.T, dir, args string) (string, bool) {
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO_RPC_GEN_MAIN="+args)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatal(err)
+	}
+	t.Logf("go-rpc-gen %s: %s", args, stderr)
+	return stdout.String(), err == nil
+}
+
+// -check exits 1 with a diff for a missing or stale file, and 0 with
+// nothing to say for an up to date one
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"core/core.go": "package core\n\nfunc Height() (uint, error) {\n\treturn 0, nil\n}\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := filepath.Join(root, "rpc")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	const args = "-server -out-pkg rpc -dir ../core -out server_methods.go"
+
+	out, ok := runMain(t, dir, args+" -check")
+	if ok || !strings.HasPrefix(out, "--- /dev/null\n+++ server_methods.go (generated)\n") {
+		t.Errorf("missing file: expected a diff and exit 1, got %v:\n%s", ok, out)
+	}
+	if _, ok := runMain(t, dir, args); !ok {
+		t.Fatal("couldn't write the file")
+	}
+
+	out, ok = runMain(t, dir, args+" -check")
+	if !ok || out != "" {
+		t.Errorf("up to date file: expected no diff and exit 0, got %v:\n%s", ok, out)
+	}
+
+	filename := filepath.Join(dir, "server_methods.go")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := strings.Replace(string(src), `"height"`, `"old_height"`, 1)
+	if err := os.WriteFile(filename, []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, ok = runMain(t, dir, args+" -check")
+	if ok || !strings.Contains(out, "\n-") || !strings.Contains(out, `"old_height"`) || !strings.Contains(out, "\n+") {
+		t.Errorf("stale file: expected a diff and exit 1, got %v:\n%s", ok, out)
+	}
+}
@@ -0,0 +1,175 @@
+package rpcgen
+
+import (
+	"fmt"
+	"go/ast"
+	gotoken "go/token"
+	"go/types"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// per function directives in the core package
+
+// directives found in a core function's doc comment, eg.
+//
+//	// rpc-gen:skip
+//	// rpc-gen:name get_block
+//	// rpc-gen:param minHeight=min_height maxHeight=max_height
+//	// rpc-gen:unsafe
+type funcDirectives struct {
+	skip   bool              // leave the function out of the rpc
+	name   string            // name on the wire
+	params map[string]string // arg name -> name on the wire
+	unsafe bool              // serve under unsafe/
+
+	namePos  gotoken.Pos // of the rpc-gen:name directive, if any
+	paramPos gotoken.Pos // of the last rpc-gen:param directive, if any
+
+	doc string // the rest of the doc comment
+}
+
+// collect the directives for each of the funcs from their doc comments
+func getFuncDirectives(fset *gotoken.FileSet, files []*ast.File, funcs map[string]*types.Func) (map[string]*funcDirectives, error) {
+	// find the declarations by position
+	decls := make(map[gotoken.Pos]*ast.FuncDecl)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if fdecl, ok := d.(*ast.FuncDecl); ok {
+				decls[fdecl.Name.Pos()] = fdecl
+			}
+		}
+	}
+
+	dirs := make(map[string]*funcDirectives)
+	for name, obj := range funcs {
+		fdecl, ok := decls[obj.Pos()]
+		if !ok || fdecl.Doc == nil {
+			continue
+		}
+		d, err := parseFuncDirectives(fset, fdecl.Doc, obj)
+		if err != nil {
+			return nil, err
+		}
+		dirs[name] = d
+	}
+	return dirs, nil
+}
+
+// parse the rpc-gen directives out of a doc comment
+func parseFuncDirectives(fset *gotoken.FileSet, doc *ast.CommentGroup, obj *types.Func) (*funcDirectives, error) {
+	d := &funcDirectives{params: make(map[string]string)}
+	for _, line := range strings.SplitAfter(doc.Text(), "\n") {
+		if !strings.HasPrefix(line, "rpc-gen:") {
+			d.doc += line
+		}
+	}
+	d.doc = strings.TrimSpace(d.doc)
+	for _, c := range doc.List {
+		// gofmt puts a space after the // since rpc-gen isn't
+		// a go directive, so accept either form
+		txt := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if !strings.HasPrefix(txt, "rpc-gen:") {
+			continue
+		}
+		fields := strings.Fields(txt[len("rpc-gen:"):])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s: empty rpc-gen directive", fset.Position(c.Pos()))
+		}
+		switch fields[0] {
+		case "skip":
+			d.skip = true
+		case "unsafe":
+			d.unsafe = true
+		case "name":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s: rpc-gen:name takes exactly one name", fset.Position(c.Pos()))
+			}
+			d.name = fields[1]
+			d.namePos = c.Pos()
+		case "param":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s: rpc-gen:param expects arg=name pairs", fset.Position(c.Pos()))
+			}
+			for _, pair := range fields[1:] {
+				spl := strings.SplitN(pair, "=", 2)
+				if len(spl) != 2 || spl[0] == "" || spl[1] == "" {
+					return nil, fmt.Errorf("%s: rpc-gen:param expects arg=name, got %q", fset.Position(c.Pos()), pair)
+				}
+				if !hasParam(obj, spl[0]) {
+					return nil, fmt.Errorf("%s: %s has no argument %s", fset.Position(c.Pos()), obj.Name(), spl[0])
+				}
+				d.params[spl[0]] = spl[1]
+			}
+			d.paramPos = c.Pos()
+		default:
+			return nil, fmt.Errorf("%s: unknown rpc-gen directive %s", fset.Position(c.Pos()), fields[0])
+		}
+	}
+	return d, nil
+}
+
+// check if the function has an argument with the given name
+func hasParam(obj *types.Func, name string) bool {
+	params := obj.Type().(*types.Signature).Params()
+	for i := 0; i < params.Len(); i++ {
+		if params.At(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// set the names used on the wire
+func (f *Func) applyDirectives(d *funcDirectives) {
+	f.WireName = CamelToLower(f.Name)
+	f.ArgWireNames = make([]string, len(f.ArgNames))
+	copy(f.ArgWireNames, f.ArgNames)
+	if d == nil {
+		return
+	}
+	f.Doc = d.doc
+	if d.name != "" {
+		f.WireName = d.name
+	}
+	if d.unsafe {
+		f.WireName = "unsafe/" + f.WireName
+	}
+	for i, n := range f.ArgNames {
+		if wn, ok := d.params[n]; ok {
+			f.ArgWireNames[i] = wn
+		}
+	}
+}
+
+// each function must have its own name on the wire, as must each of
+// its args. a clash is reported at the directive that made it, if any
+func checkWireNames(fset *gotoken.FileSet, funcs []*Func, objs map[string]*types.Func, dirs map[string]*funcDirectives) error {
+	served := make(map[string]string)
+	for _, f := range funcs {
+		d := dirs[f.Name]
+		if other, ok := served[f.WireName]; ok {
+			pos := objs[f.Name].Pos()
+			if d != nil && d.namePos.IsValid() {
+				pos = d.namePos
+			} else if od := dirs[other]; od != nil && od.namePos.IsValid() {
+				pos = od.namePos
+			}
+			return fmt.Errorf("%s: %s and %s are both named %q on the wire", fset.Position(pos), other, f.Name, f.WireName)
+		}
+		served[f.WireName] = f.Name
+
+		args := make(map[string]string)
+		for i, wn := range f.ArgWireNames {
+			if other, ok := args[wn]; ok {
+				pos := objs[f.Name].Pos()
+				if d != nil && d.paramPos.IsValid() {
+					pos = d.paramPos
+				}
+				return fmt.Errorf("%s: %s has args %s and %s both named %q on the wire", fset.Position(pos), f.Name, other, f.ArgNames[i], wn)
+			}
+			args[wn] = f.ArgNames[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+// Package rpcgen generates rpc clients, and the server side to go with them,
+// from the exported functions of a core package and templates written in
+// comments of the package being generated for. It's what go-rpc-gen runs,
+// for use from build tools and tests:
+//
+//	src, err := rpcgen.Generate(rpcgen.Config{
+//		Dir:      "rpc",
+//		OutPkg:   "rpc",
+//		Types:    []string{"*ClientHTTP", "*ClientJSON"},
+//		CoreDir:  "../core",
+//		Excludes: []string{"pipe.go"},
+//	})
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/scanner"
+	gotoken "go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Config is what to generate, and from where. Paths are relative to Dir
+type Config struct {
+	Dir     string // directory of the package being generated for (default ".")
+	OutPkg  string // name of the package being generated for
+	OutFile string // the file being generated, left out when reading the package in Dir (default client_methods.go)
+
+	Interface string   // interface type to define the rpc methods on (default the rpc-gen:define-interface)
+	Types     []string // types that should implement the interface, eg. *ClientHTTP
+	Templates []string // template files, or directories of .tmpl files (in addition to templates in comments)
+
+	CoreDir  string   // directory of the package with the core functionality for the rpc
+	CorePkg  string   // name to qualify the core package with (default its package name)
+	Excludes []string // files in CoreDir whose functions are left out of the rpc
+	Service  string   // receiver type whose exported methods are used instead of the package's functions (eg. *core.Service)
+
+	Local   string // name of a client to generate that calls the core functions directly, in process, eg. ClientLocal
+	Mock    string // name of a mock implementation of the interface to generate along with the clients, eg. MockClient
+	Server  bool   // generate the server-side handler table instead of the client methods
+	Typed   bool   // with Server, also generate typed handlers which call the functions without reflection, and serve them
+	Command string // the command line recorded in the generated code's header (default go-rpc-gen)
+}
+
+// the path relative to Dir
+func (cfg Config) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(cfg.Dir, p)
+}
+
+// Generate the client methods (or with Server, the server's handler table)
+// described by cfg, returning the gofmt'd source of the file. Errors in the
+// templates are reported compiler style, at their file, line and column
+func Generate(cfg Config) ([]byte, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = "."
+	}
+	if cfg.OutFile == "" {
+		cfg.OutFile = "client_methods.go"
+	}
+	if cfg.Command == "" {
+		cfg.Command = "go-rpc-gen"
+	}
+	if cfg.OutPkg == "" {
+		return nil, fmt.Errorf("no output package")
+	}
+	if cfg.CoreDir == "" {
+		return nil, fmt.Errorf("no core package directory")
+	}
+	if cfg.Typed && !cfg.Server {
+		return nil, fmt.Errorf("typed handlers are only generated for the server")
+	}
+	if !cfg.Server && len(cfg.Types) == 0 && cfg.Local == "" && cfg.Mock == "" {
+		return nil, fmt.Errorf("no types to implement the interface")
+	}
+	for _, t := range append([]string{cfg.Mock}, cfg.Types...) {
+		if cfg.Local != "" && strings.TrimPrefix(t, "*") == cfg.Local {
+			return nil, fmt.Errorf("%s is both the local client and another type to generate", cfg.Local)
+		}
+	}
+	outFile := cfg.path(cfg.OutFile)
+	coreDir := cfg.path(cfg.CoreDir)
+
+	fset := gotoken.NewFileSet() // positions are relative to fset
+	// imports are resolved from Dir, wherever we're run from
+	imp, err := newSrcImporter(fset, cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the core functions to be exposed
+	corePkgImportPath, err := goImportPathFromDir(coreDir, cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	corePkg, coreFiles, err := loadPackage(fset, imp, coreDir, corePkgImportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the interface to be populated (present in Dir), and init the
+	// rpc generator by parsing the templates and definitions.
+	// the server needs neither
+	var pkg *ast.Package
+	var rpcGen *RpcGen
+	if !cfg.Server {
+		// the output file is left out, it's about to be replaced
+		notOut := func(fi fs.FileInfo) bool {
+			return filepath.Join(cfg.Dir, fi.Name()) != outFile
+		}
+		pkgs, err := goparser.ParseDir(fset, cfg.Dir, notOut, goparser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if pkg, err = onePkg(pkgs); err != nil {
+			return nil, err
+		}
+		if rpcGen, err = initRpcGen(fset, pkg); err != nil {
+			return nil, err
+		}
+		templateFiles := []string{}
+		for _, t := range cfg.Templates {
+			templateFiles = append(templateFiles, cfg.path(t))
+		}
+		if err := rpcGen.loadTemplateFiles(templateFiles); err != nil {
+			return nil, err
+		}
+		for _, clientType := range cfg.Types {
+			if _, ok := rpcGen.templates[clientType]; !ok {
+				return nil, fmt.Errorf("no template for %s", clientType)
+			}
+		}
+	}
+
+	// track the imports needed by the core types.
+	// types from the package being generated are never qualified
+	outPkgImportPath, _ := goImportPathFromDir(cfg.Dir, cfg.Dir)
+	imps := newImportSet(outPkgImportPath)
+	if rpcGen != nil {
+		// the template imports keep their names, so they're reserved before
+		// anything else is named. the core package is renamed if it clashes
+		if err := imps.addAll(rpcGen.imports); err != nil {
+			return nil, err
+		}
+	}
+	pkgName := cfg.CorePkg
+	if pkgName == "" {
+		pkgName = corePkg.Name()
+	}
+	pkgName = imps.add(pkgName, corePkgImportPath)
+	if rpcGen != nil {
+		if err := rpcGen.resolveSets(fset, imp, setImports(rpcGen.imports, pkgName, corePkg, coreFiles)); err != nil {
+			return nil, err
+		}
+		rpcGen.resolveFuncDefs(fset, imp, pkg, outPkgImportPath)
+	}
+
+	// expose either the methods of the service or the package's functions
+	var coreFuncs map[string]*types.Func
+	service := ""
+	if cfg.Service != "" {
+		recv, err := lookupService(corePkg, cfg.Service)
+		if err != nil {
+			return nil, err
+		}
+		coreFuncs = getMethods(fset, recv, cfg.Excludes)
+		service = types.TypeString(recv, imps.qualify)
+	} else {
+		coreFuncs = getFuncs(fset, corePkg, cfg.Excludes)
+	}
+	dirs, err := getFuncDirectives(fset, coreFiles, coreFuncs)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Server {
+		// the server only needs the funcs and their arg names
+		stringFuncs, _, err := populateInterface(fset, "}", coreFuncs, dirs, imps)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Typed {
+			if err := checkTyped(stringFuncs); err != nil {
+				return nil, err
+			}
+		}
+		body := new(bytes.Buffer)
+		if service != "" {
+			writeServiceServer(body, stringFuncs, service, cfg.Typed)
+		} else {
+			writeServer(body, stringFuncs, pkgName, cfg.Typed)
+		}
+		imports := map[string]string{
+			"http":  "net/http",
+			pkgName: corePkgImportPath,
+		}
+		if cfg.Typed {
+			// the typed handlers declare their args, so they need the
+			// imports of the core types too
+			imports = imps.imports()
+			imports["http"] = "net/http"
+			imports["context"] = "context"
+			imports = usedImports(imports, body.Bytes())
+		}
+		buf := new(bytes.Buffer)
+		writeHeader(buf, cfg.Command, cfg.OutPkg, imports)
+		buf.Write(body.Bytes())
+		return formatGoFile(fset, outFile, buf.Bytes())
+	}
+
+	// start from the base interface, if one was defined
+	iface := cfg.Interface
+	if iface == "" {
+		iface = rpcGen.ifaceName
+	}
+	if iface == "" {
+		return nil, fmt.Errorf("no interface name, and no rpc-gen:define-interface")
+	}
+	interfaceDef := fmt.Sprintf(`
+type %s interface{
+
+}`, iface)
+	baseMethods := []string{}
+	var baseIface *types.Interface
+	if rpcGen.ifaceDef != "" {
+		if rpcGen.ifaceName != iface {
+			return nil, fmt.Errorf("rpc-gen:define-interface defines %s but the interface is %s", rpcGen.ifaceName, iface)
+		}
+		defFile, it, err := rpcGen.parseBaseInterface(fset)
+		if err != nil {
+			return nil, err
+		}
+		baseIface, err = checkImplements(fset, imp, pkg, outPkgImportPath, outFile, defFile, iface, cfg.Types)
+		if err != nil {
+			return nil, err
+		}
+		if err := baseImports(defFile, it, imps); err != nil {
+			return nil, err
+		}
+		interfaceDef = rpcGen.ifaceDef
+		baseMethods = baseMethodNames(it)
+	}
+
+	// populate interface and stringify func defs
+	stringFuncs, interfaceDef, err := populateInterface(fset, interfaceDef, coreFuncs, dirs, imps)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range stringFuncs {
+		for _, m := range baseMethods {
+			if f.Name == m {
+				return nil, fmt.Errorf("%s is both a base method of %s and a core function", m, iface)
+			}
+		}
+	}
+
+	// for each client type, implement the interface
+	// using its template and the stringFuncs.
+	// templates may need more imports, so the header comes after
+	rpcGen.imps = imps
+	rpcGen.pkgName = pkgName
+	implementations := new(bytes.Buffer)
+	for _, clientType := range cfg.Types {
+		implementation, err := rpcGen.implementInterface(clientType, stringFuncs)
+		if err != nil {
+			return nil, err
+		}
+		// write implementation to buffer
+		implementations.Write(implementation)
+	}
+	if cfg.Local != "" {
+		if err := checkLocalNames(cfg.Local, service != "", stringFuncs); err != nil {
+			return nil, err
+		}
+		writeLocal(implementations, cfg.Local, iface, pkgName, service, stringFuncs)
+	}
+	if cfg.Mock != "" {
+		// the base methods, then the core functions
+		methods := []mockMethod{}
+		if baseIface != nil {
+			methods = baseMockMethods(baseIface, imps.qualify)
+		}
+		for _, f := range stringFuncs {
+			methods = append(methods, funcMockMethod(f))
+		}
+		if err := checkMockNames(cfg.Mock, methods); err != nil {
+			return nil, err
+		}
+		writeMock(implementations, cfg.Mock, iface, imps.add("sync", "sync"), methods)
+	}
+	// only import what the generated code uses,
+	// since not every template uses every import
+	neededImports := usedImports(imps.imports(), append([]byte(interfaceDef+"\n"), implementations.Bytes()...))
+
+	buf := new(bytes.Buffer)
+	writeHeader(buf, cfg.Command, cfg.OutPkg, neededImports)
+	fmt.Fprint(buf, interfaceDef)
+	buf.Write(implementations.Bytes())
+	return formatGoFile(fset, outFile, buf.Bytes())
+}
+
+// write the generated code header, the package clause and the imports
+func writeHeader(buf *bytes.Buffer, command, outPkg string, imports map[string]string) {
+	fmt.Fprintf(buf, "// Code generated by %q; DO NOT EDIT.\n", command)
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "package", outPkg)
+	fmt.Fprintln(buf, "")
+	writeImports(buf, imports)
+}
+
+// parse the generated source text for the sake of gofmt
+func formatGoFile(fset *gotoken.FileSet, outFile string, data []byte) ([]byte, error) {
+	node, err := goparser.ParseFile(fset, outFile, data, goparser.ParseComments)
+	if err != nil {
+		return nil, invalidSourceError(outFile, data, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := format.Node(buf, fset, node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// the generated code isn't on disk, so show the offending line
+func invalidSourceError(outFile string, data []byte, err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("%s: generated code is invalid: %v", outFile, err)
+	}
+	pos := list[0].Pos
+	lines := strings.Split(string(data), "\n")
+	line := ""
+	if pos.Line > 0 && pos.Line <= len(lines) {
+		line = lines[pos.Line-1]
+	}
+	return fmt.Errorf("%s: generated code is invalid: line %d:%d: %s\n\t%s",
+		outFile, pos.Line, pos.Column, list[0].Msg, strings.TrimSpace(line))
+}
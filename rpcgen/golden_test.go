@@ -0,0 +1,186 @@
+package rpcgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden files in testdata")
+
+// each directory in testdata is a case: a core package (core), the package
+// being generated for (rpc), and config.json, the Config to generate with
+// (Dir is always rpc). the expected output is in out.golden or, if generating
+// should fail, the expected error is in err.golden. the output must build
+// along with the rest of the rpc package, so it's type checked with it.
+// a case with a go.mod is a module, otherwise it's on the $GOPATH.
+//
+//	go test -run Golden -update
+//
+// regenerates the golden files
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			continue
+		}
+		dir := dir
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			testGolden(t, dir)
+		})
+	}
+}
+
+func testGolden(t *testing.T, dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("config.json: %v", err)
+	}
+	cfg.Dir = filepath.Join(dir, "rpc")
+
+	// the output, or the error
+	var got []byte
+	golden, other := "out.golden", "err.golden"
+	// a case with its own go.mod imports its packages from the module
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		t.Setenv("GO111MODULE", "on")
+	}
+	src, err := Generate(cfg)
+	if err != nil {
+		got = []byte(err.Error() + "\n")
+		golden, other = other, golden
+	} else {
+		got = src
+	}
+	golden, other = filepath.Join(dir, golden), filepath.Join(dir, other)
+	if src != nil {
+		typeCheckGolden(t, cfg, src)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(other); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if os.IsNotExist(err) {
+		if _, err := os.Stat(other); err == nil {
+			t.Fatalf("expected %s, got:\n%s", filepath.Base(other), got)
+		}
+		t.Fatalf("no %s, run go test -update to create it. got:\n%s", filepath.Base(golden), got)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		line, g, w := firstDiff(string(got), string(want))
+		t.Errorf("%s differs from line %d:\ngot:  %s\nwant: %s\n(run go test -update to accept the new output)",
+			golden, line, g, w)
+	}
+}
+
+// type check the generated code with the rest of the package it's generated for
+func typeCheckGolden(t *testing.T, cfg Config, src []byte) {
+	outFile := cfg.OutFile
+	if outFile == "" {
+		outFile = "client_methods.go"
+	}
+	fset := gotoken.NewFileSet()
+	imp, err := newSrcImporter(fset, cfg.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := goparser.ParseFile(fset, filepath.Join(cfg.Dir, outFile), src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*ast.File{out}
+	paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if filepath.Base(path) == outFile || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	importPath, err := goImportPathFromDir(cfg.Dir, cfg.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []string
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			errs = append(errs, err.Error())
+		},
+	}
+	pkg, _ := conf.Check(importPath, fset, files, nil)
+	if len(errs) > 0 {
+		t.Errorf("the generated code doesn't build:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	// the importer loads the rpc package without the output, so a cycle
+	// through it isn't an error to the type checker
+	if p := importedBy(pkg, importPath, map[string]bool{}); p != "" {
+		t.Errorf("the generated code doesn't build: import cycle through %s", p)
+	}
+}
+
+// the package imported by pkg, directly or not, that imports path, if any
+func importedBy(pkg *types.Package, path string, seen map[string]bool) string {
+	for _, imp := range pkg.Imports() {
+		if imp.Path() == path {
+			return pkg.Path()
+		}
+		if seen[imp.Path()] {
+			continue
+		}
+		seen[imp.Path()] = true
+		if p := importedBy(imp, path, seen); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// the first line that differs, as it is in each
+func firstDiff(got, want string) (int, string, string) {
+	g, w := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; ; i++ {
+		if i >= len(g) || i >= len(w) || g[i] != w[i] {
+			gl, wl := "<EOF>", "<EOF>"
+			if i < len(g) {
+				gl = g[i]
+			}
+			if i < len(w) {
+				wl = w[i]
+			}
+			return i + 1, gl, wl
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package rpcgen
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// resolve import paths from go.mod and go.work files
+
+// the parts of a go.mod or go.work file we care about
+type modFile struct {
+	dir      string // directory containing the file
+	module   string // module path (go.mod only)
+	replaces []modReplace
+}
+
+// a replace directive pointing at a directory on disk
+type modReplace struct {
+	oldPath string // module path being replaced
+	dir     string // absolute path of the replacement
+}
+
+// get the import path of the package in dir. Replace directives in the
+// main module (or workspace), the one containing mainDir, take precedence,
+// then the nearest enclosing go.mod, and finally the $GOPATH
+func goImportPathFromDir(dir, mainDir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// the module containing dir
+	mod, err := findModFile(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// replace directives in the module/workspace we're generating for,
+	// unless dir is in a module nested below the replacement
+	if wd, err := filepath.Abs(mainDir); err == nil {
+		mains := []*modFile{}
+		if work, err := findWorkFile(wd); err != nil {
+			return "", err
+		} else if work != nil {
+			mains = append(mains, work)
+		}
+		if main, err := findModFile(wd); err != nil {
+			return "", err
+		} else if main != nil {
+			mains = append(mains, main)
+		}
+		for _, m := range mains {
+			for _, r := range m.replaces {
+				rel, ok := relDir(r.dir, dir)
+				if !ok {
+					continue
+				}
+				if mod != nil && mod.dir != r.dir {
+					if _, nested := relDir(r.dir, mod.dir); nested {
+						continue
+					}
+				}
+				return joinImportPath(r.oldPath, rel), nil
+			}
+		}
+	}
+
+	if mod != nil {
+		if mod.module == "" {
+			return "", fmt.Errorf("%s has no module directive", filepath.Join(mod.dir, "go.mod"))
+		}
+		rel, _ := relDir(mod.dir, dir)
+		return joinImportPath(mod.module, rel), nil
+	}
+
+	return goPathImportPathFromDir(dir)
+}
+
+// get the $GOPATH relative path from the dir
+func goPathImportPathFromDir(dir string) (string, error) {
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		if rel, ok := relDir(filepath.Join(gopath, "src"), dir); ok && rel != "" {
+			return rel, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in a module and not on the $GOPATH", dir)
+}
+
+// find and parse the go.mod for the module containing dir.
+// returns nil if there is none
+func findModFile(dir string) (*modFile, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		f := filepath.Join(d, "go.mod")
+		if _, err := os.Stat(f); err == nil {
+			return parseModFile(f)
+		}
+		if filepath.Dir(d) == d {
+			return nil, nil
+		}
+	}
+}
+
+// find and parse the go.work for the workspace containing dir,
+// respecting $GOWORK. returns nil if there is none.
+// modules used by the workspace are found by their own go.mod
+func findWorkFile(dir string) (*modFile, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return nil, nil
+	case "":
+	default:
+		return parseModFile(gowork)
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		f := filepath.Join(d, "go.work")
+		if _, err := os.Stat(f); err == nil {
+			return parseModFile(f)
+		}
+		if filepath.Dir(d) == d {
+			return nil, nil
+		}
+	}
+}
+
+// parse the module and replace directives from a go.mod or go.work
+func parseModFile(filename string) (*modFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mod := &modFile{dir: filepath.Dir(filename)}
+	block := "" // the directive of the enclosing ( ) block
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields, err := modFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, n, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		verb := block
+		if block == "" {
+			verb, fields = fields[0], fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				block = verb
+				continue
+			}
+		} else if fields[0] == ")" {
+			block = ""
+			continue
+		}
+
+		switch verb {
+		case "module":
+			if len(fields) > 0 {
+				mod.module = fields[0]
+			}
+		case "replace":
+			// old [version] => new [version]
+			i := 0
+			for ; i < len(fields) && fields[i] != "=>"; i++ {
+			}
+			if i == 0 || i+1 >= len(fields) {
+				return nil, fmt.Errorf("%s:%d: malformed replace directive", filename, n)
+			}
+			if newPath := fields[i+1]; isLocalModPath(newPath) {
+				mod.replaces = append(mod.replaces, modReplace{
+					oldPath: fields[0],
+					dir:     mod.localDir(newPath),
+				})
+			}
+		}
+	}
+	return mod, scanner.Err()
+}
+
+// split a go.mod line into fields, unquoting quoted strings
+func modFields(line string) ([]string, error) {
+	fields := []string{}
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '"' || line[0] == '`' {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, err
+			}
+			s, _ := strconv.Unquote(q)
+			fields = append(fields, s)
+			line = line[len(q):]
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			i = len(line)
+		}
+		fields = append(fields, line[:i])
+		line = line[i:]
+	}
+	return fields, nil
+}
+
+// replacements are local directories if they look like file paths
+func isLocalModPath(p string) bool {
+	return filepath.IsAbs(p) || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") ||
+		p == "." || p == ".."
+}
+
+// resolve a path relative to the mod file's directory
+func (mod *modFile) localDir(p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(mod.dir, filepath.FromSlash(p))
+}
+
+// return the slash separated path of dir relative to root,
+// and whether dir is in root at all
+func relDir(root, dir string) (string, bool) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		rel = ""
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func joinImportPath(modPath, rel string) string {
+	if rel == "" {
+		return modPath
+	}
+	return path.Join(modPath, rel)
+}
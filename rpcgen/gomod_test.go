@@ -0,0 +1,208 @@
+package rpcgen
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// each case is a tree of files written to a temp dir, which is also the
+// only $GOPATH. dir and mainDir are relative to it, as is $GOWORK if it
+// isn't "" or "off". the expected error is a substring of the error
+var importPathTests = []struct {
+	name    string
+	files   map[string]string
+	dir     string
+	mainDir string
+	gowork  string
+	want    string
+	err     string
+}{
+	{
+		name:  "module root",
+		files: map[string]string{"m/go.mod": "module example.com/m\n\ngo 1.21\n"},
+		dir:   "m",
+		want:  "example.com/m",
+	},
+	{
+		name:  "package in a module",
+		files: map[string]string{"m/go.mod": "module example.com/m\n"},
+		dir:   "m/a/b",
+		want:  "example.com/m/a/b",
+	},
+	{
+		name:  "quoted module path and comments",
+		files: map[string]string{"m/go.mod": "// the module\nmodule \"example.com/m\" // quoted\n"},
+		dir:   "m/a",
+		want:  "example.com/m/a",
+	},
+	{
+		name:  "no module directive",
+		files: map[string]string{"m/go.mod": "go 1.21\n"},
+		dir:   "m/a",
+		err:   "has no module directive",
+	},
+	{
+		name: "replace",
+		files: map[string]string{
+			"m/go.mod":   "module example.com/m\n\nreplace example.com/dep => ../dep\n",
+			"dep/dep.go": "package dep\n",
+		},
+		dir:     "dep/x",
+		mainDir: "m",
+		want:    "example.com/dep/x",
+	},
+	{
+		name: "replace block with versions",
+		files: map[string]string{
+			"m/go.mod": "module example.com/m\n\nreplace (\n\texample.com/old v1.0.0 => example.com/new v1.1.0\n" +
+				"\texample.com/dep v1.2.0 => ../dep v0.0.0\n)\n",
+			"dep/go.mod": "module example.com/renamed\n",
+		},
+		dir:     "dep/x",
+		mainDir: "m",
+		want:    "example.com/dep/x",
+	},
+	{
+		name: "replace of another module isn't used",
+		files: map[string]string{
+			"m/go.mod":   "module example.com/m\n",
+			"n/go.mod":   "module example.com/n\n\nreplace example.com/dep => ../dep\n",
+			"dep/go.mod": "module example.com/dep/v2\n",
+		},
+		dir:     "dep/x",
+		mainDir: "m",
+		want:    "example.com/dep/v2/x",
+	},
+	{
+		name:  "malformed replace",
+		files: map[string]string{"m/go.mod": "module example.com/m\n\nreplace example.com/dep =>\n"},
+		dir:   "m",
+		err:   "go.mod:3: malformed replace directive",
+	},
+	{
+		name: "nested module",
+		files: map[string]string{
+			"m/go.mod":     "module example.com/m\n",
+			"m/sub/go.mod": "module example.com/sub\n",
+		},
+		dir:     "m/sub/x",
+		mainDir: "m",
+		want:    "example.com/sub/x",
+	},
+	{
+		name: "nested module in a replacement",
+		files: map[string]string{
+			"m/go.mod":          "module example.com/m\n\nreplace example.com/dep => ../dep\n",
+			"dep/nested/go.mod": "module example.com/nested\n",
+		},
+		dir:     "dep/nested/x",
+		mainDir: "m",
+		want:    "example.com/nested/x",
+	},
+	{
+		name: "workspace",
+		files: map[string]string{
+			"go.work":  "go 1.21\n\nuse (\n\t./a\n\t./b\n)\n\nreplace example.com/dep => ./dep\n",
+			"a/go.mod": "module example.com/a\n",
+			"b/go.mod": "module example.com/b\n",
+		},
+		dir:     "dep/x",
+		mainDir: "a",
+		want:    "example.com/dep/x",
+	},
+	{
+		name: "module used by the workspace",
+		files: map[string]string{
+			"go.work":  "go 1.21\n\nuse (\n\t./a\n\t./b\n)\n",
+			"a/go.mod": "module example.com/a\n",
+			"b/go.mod": "module example.com/b\n",
+		},
+		dir:     "b/x",
+		mainDir: "a",
+		want:    "example.com/b/x",
+	},
+	{
+		name: "workspace from $GOWORK",
+		files: map[string]string{
+			"w/ws.work": "go 1.21\n\nuse ../a\n\nreplace example.com/dep => ../dep\n",
+			"a/go.mod":  "module example.com/a\n",
+		},
+		dir:     "dep/x",
+		mainDir: "a",
+		gowork:  "w/ws.work",
+		want:    "example.com/dep/x",
+	},
+	{
+		name: "workspace off",
+		files: map[string]string{
+			"go.work":  "go 1.21\n\nuse ./a\n\nreplace example.com/dep => ./dep\n",
+			"a/go.mod": "module example.com/a\n",
+		},
+		dir:     "dep/x",
+		mainDir: "a",
+		gowork:  "off",
+		err:     "is not in a module and not on the $GOPATH",
+	},
+	{
+		name:  "$GOPATH",
+		files: map[string]string{"src/example.com/g/g.go": "package g\n"},
+		dir:   "src/example.com/g/p",
+		want:  "example.com/g/p",
+	},
+	{
+		name:  "$GOPATH src",
+		files: map[string]string{"src/x.go": "package x\n"},
+		dir:   "src",
+		err:   "is not in a module and not on the $GOPATH",
+	},
+	{
+		name:  "nowhere",
+		files: map[string]string{"x/x.go": "package x\n"},
+		dir:   "x",
+		err:   "is not in a module and not on the $GOPATH",
+	},
+}
+
+func TestGoImportPathFromDir(t *testing.T) {
+	defer func(gopath string) { build.Default.GOPATH = gopath }(build.Default.GOPATH)
+	for _, test := range importPathTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range test.files {
+				filename := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			build.Default.GOPATH = root
+			gowork := test.gowork
+			if gowork != "" && gowork != "off" {
+				gowork = filepath.Join(root, filepath.FromSlash(gowork))
+			}
+			t.Setenv("GOWORK", gowork)
+
+			mainDir := test.mainDir
+			if mainDir == "" {
+				mainDir = test.dir
+			}
+			got, err := goImportPathFromDir(filepath.Join(root, test.dir), filepath.Join(root, mainDir))
+			switch {
+			case test.err != "" && err == nil:
+				t.Errorf("expected an error containing %q, got %s", test.err, got)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Errorf("expected an error containing %q, got %q", test.err, err)
+			case test.err == "" && err != nil:
+				t.Error(err)
+			case got != test.want:
+				t.Errorf("got %s, expected %s", got, test.want)
+			}
+		})
+	}
+}
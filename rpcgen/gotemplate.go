@@ -0,0 +1,233 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+//--------------------------------------------------------------------------------
+// templates written with text/template (rpc-gen:gotemplate)
+
+// the data a gotemplate is executed with, once for each function, eg.
+//
+//	func (c *ClientHTTP) {{.Name}}({{.Signature}}) ({{.Results}}) {
+//		values := url.Values{}{{range .Args}}
+//		values.Set({{quote .WireName}}, {{encode "wire" .}}){{end}}
+//		...
+//	}
+type TemplateFunc struct {
+	Client    string           // the client type being implemented, eg. *ClientHTTP
+	Name      string           // name of the function, eg. GetBlock
+	WireName  string           // name of the function on the wire, eg. get_block
+	Args      []TemplateArg    // the args that go over the wire (not the context)
+	Returns   []TemplateReturn // the return values
+	Variadic  bool             // the last arg is variadic
+	Doc       string           // doc comment of the core function, without the rpc-gen directives
+	Pkg       string           // qualifier of the core package in the generated code, eg. core
+	CtxName   string           // name of the context arg, if the function takes one
+	Signature string           // the args as declared, including any context, eg. ctx context.Context, height uint
+	Results   string           // the return types, comma separated
+
+	rg *RpcGen
+}
+
+// an arg of the function
+type TemplateArg struct {
+	Name      string // name in the generated code
+	Type      string // type as printed in the generated code, ...T if variadic
+	WireName  string // name on the wire
+	IsSlice   bool   // the underlying type is a slice (or the arg is variadic)
+	IsPointer bool   // the underlying type is a pointer
+
+	gotype types.Type
+}
+
+// a return value of the function
+type TemplateReturn struct {
+	Type      string // type as printed in the generated code
+	IsError   bool   // the type is error
+	IsSlice   bool   // the underlying type is a slice
+	IsPointer bool   // the underlying type is a pointer
+
+	gotype types.Type
+}
+
+// the context to make the call with: the function's context,
+// or a fresh one if it doesn't take one
+func (tf TemplateFunc) Ctx() string {
+	if tf.CtxName != "" {
+		return tf.CtxName
+	}
+	return tf.rg.imps.add("context", "context") + ".Background()"
+}
+
+// the last return value is an error
+func (tf TemplateFunc) HasError() bool {
+	return len(tf.Returns) > 0 && tf.Returns[len(tf.Returns)-1].IsError
+}
+
+// the data for the function
+func (rg *RpcGen) templateFunc(f Func) TemplateFunc {
+	tf := TemplateFunc{
+		Client:    rg.clientType,
+		Name:      f.Name,
+		WireName:  f.WireName,
+		Args:      []TemplateArg{},
+		Returns:   []TemplateReturn{},
+		Variadic:  f.Variadic,
+		Doc:       f.Doc,
+		Pkg:       rg.pkgName,
+		CtxName:   f.CtxName,
+		Signature: f.argsDef(),
+		Results:   strings.Join(f.ReturnTypes, ", "),
+		rg:        rg,
+	}
+	for i, n := range f.ArgNames {
+		_, slice := f.params[i].Underlying().(*types.Slice)
+		_, ptr := f.params[i].Underlying().(*types.Pointer)
+		tf.Args = append(tf.Args, TemplateArg{
+			Name:      n,
+			Type:      f.ArgTypes[i],
+			WireName:  f.ArgWireNames[i],
+			IsSlice:   slice,
+			IsPointer: ptr,
+			gotype:    f.params[i],
+		})
+	}
+	for i, t := range f.ReturnTypes {
+		r := f.results[i]
+		_, slice := r.Underlying().(*types.Slice)
+		_, ptr := r.Underlying().(*types.Pointer)
+		tf.Returns = append(tf.Returns, TemplateReturn{
+			Type:      t,
+			IsError:   types.Identical(r, types.Universe.Lookup("error").Type()),
+			IsSlice:   slice,
+			IsPointer: ptr,
+			gotype:    r,
+		})
+	}
+	return tf
+}
+
+// functions available to gotemplates, on top of the text/template builtins:
+//
+//	quote "s"                    the go string literal for s
+//	helper "name" args...        a call to a define-func helper
+//	encode "set" args...         the set's encoder calls for the args
+//	decode "set" ret expr...     the set's decoder call for the return value's type
+//
+// args are a TemplateArg, a []TemplateArg (eg. .Args), a TemplateReturn or a string of go code
+func (rg *RpcGen) templateFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"quote": strconv.Quote,
+		"helper": func(name string, vals ...interface{}) (string, error) {
+			def, ok := rg.funcdefs[name]
+			if !ok {
+				return "", fmt.Errorf("unknown helper %s", name)
+			}
+			ops, err := toOperands(vals)
+			if err != nil {
+				return "", err
+			}
+			return defToCall(def, rg.cur, ops)
+		},
+		"encode": func(name string, vals ...interface{}) (string, error) {
+			set, ok := rg.sets[name]
+			if !ok {
+				return "", fmt.Errorf("unknown set %s", name)
+			}
+			ops, err := toOperands(vals)
+			if err != nil {
+				return "", err
+			}
+			return setToEncodeCalls(set, ops)
+		},
+		"decode": func(name string, vals ...interface{}) (string, error) {
+			set, ok := rg.sets[name]
+			if !ok {
+				return "", fmt.Errorf("unknown set %s", name)
+			}
+			ops, err := toOperands(vals)
+			if err != nil {
+				return "", err
+			}
+			return setToDecodeCall(set, ops)
+		},
+	}
+}
+
+// convert the values passed to a template function to operands
+func toOperands(vals []interface{}) ([]operand, error) {
+	ops := []operand{}
+	for _, v := range vals {
+		switch v := v.(type) {
+		case TemplateArg:
+			ops = append(ops, operand{src: v.Name, typ: v.Type, gotype: v.gotype, ref: "args"})
+		case []TemplateArg:
+			for _, a := range v {
+				ops = append(ops, operand{src: a.Name, typ: a.Type, gotype: a.gotype, ref: "args"})
+			}
+		case TemplateReturn:
+			ops = append(ops, operand{src: v.Type, typ: v.Type, gotype: v.gotype, ref: "response"})
+		case string:
+			ops = append(ops, operand{src: v})
+		default:
+			return nil, fmt.Errorf("unexpected %T, expected an arg, a return value or go code", v)
+		}
+	}
+	return ops, nil
+}
+
+// parse the client type's gotemplate
+func (rg *RpcGen) parseGoTemplate(clientType, tmp string) (*template.Template, error) {
+	t, err := template.New(clientType).Funcs(rg.templateFuncMap()).Parse(tmp)
+	if err != nil {
+		return nil, goTemplateError(rg.templateLocs[clientType], tmp, err, "")
+	}
+	return t, nil
+}
+
+// execute the gotemplate for the function
+func (rg *RpcGen) execGoTemplate(buf *bytes.Buffer, t *template.Template, f Func) error {
+	rg.cur = f
+	if err := t.Execute(buf, rg.templateFunc(f)); err != nil {
+		return goTemplateError(rg.templateLocs[rg.clientType], rg.templates[rg.clientType], err, f.Name)
+	}
+	return nil
+}
+
+// eg. template: *ClientHTTP:3:16: executing ...
+var goTemplateErrorRe = regexp.MustCompile(`^template: [^:]*:(\d+)(?::(\d+))?: ((?s).*)$`)
+
+// text/template errors are relative to the start of the template, tmp,
+// so locate them in the template's file instead
+func goTemplateError(loc location, tmp string, err error, funcName string) error {
+	msg := err.Error()
+	if m := goTemplateErrorRe.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		col := 0 // text/template columns are bytes, from 0
+		if m[2] != "" {
+			col, _ = strconv.Atoi(m[2])
+			if lines := strings.Split(tmp, "\n"); line <= len(lines) && col <= len(lines[line-1]) {
+				col = utf8.RuneCountInString(lines[line-1][:col])
+			}
+		}
+		if line == 1 {
+			loc.col += col
+		} else {
+			loc.line += line - 1
+			loc.col = col + 1
+		}
+		msg = m[3]
+	}
+	if funcName != "" {
+		msg += " (implementing " + funcName + ")"
+	}
+	return &templateError{loc: loc, msg: msg}
+}
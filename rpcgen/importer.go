@@ -0,0 +1,93 @@
+package rpcgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"path/filepath"
+)
+
+//--------------------------------------------------------------------------------
+// type check imported packages from source
+
+// a types.ImporterFrom type checking the packages it imports from source.
+// they're found with a copy of the default build context whose Dir is the
+// one being generated for, so go list resolves module imports from there
+// rather than from the process's working directory
+type srcImporter struct {
+	fset *gotoken.FileSet
+	ctxt build.Context
+	pkgs map[string]*types.Package // by dir, nil while it's being checked
+}
+
+func newSrcImporter(fset *gotoken.FileSet, dir string) (*srcImporter, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctxt := build.Default
+	ctxt.Dir = dir
+	// only the signatures matter, so the go files will do
+	ctxt.CgoEnabled = false
+	return &srcImporter{
+		fset: fset,
+		ctxt: ctxt,
+		pkgs: make(map[string]*types.Package),
+	}, nil
+}
+
+func (imp *srcImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, imp.ctxt.Dir, 0)
+}
+
+func (imp *srcImporter) ImportFrom(path, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	// go/build won't take a relative srcDir with the context's Dir set
+	srcDir, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	bpkg, err := imp.ctxt.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.pkgs[bpkg.Dir]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", bpkg.ImportPath)
+		}
+		return pkg, nil
+	}
+	imp.pkgs[bpkg.Dir] = nil
+
+	files := []*ast.File{}
+	for _, name := range bpkg.GoFiles {
+		f, err := goparser.ParseFile(imp.fset, filepath.Join(bpkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	var firstErr error
+	conf := types.Config{
+		Importer:         imp,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(bpkg.ImportPath, imp.fset, files, nil)
+	if firstErr != nil {
+		delete(imp.pkgs, bpkg.Dir)
+		return nil, fmt.Errorf("type checking %s: %v", bpkg.ImportPath, firstErr)
+	}
+	imp.pkgs[bpkg.Dir] = pkg
+	return pkg, nil
+}
@@ -0,0 +1,165 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// track the imports needed by the generated code
+
+// the set of imports needed by the generated code.
+// qualify is used as a types.Qualifier, so every package
+// referenced while printing a type is added to the set
+type importSet struct {
+	names map[string]string // import name -> path
+	paths map[string]string // path -> import name
+
+	localPath string // path of the package being generated (never imported)
+}
+
+func newImportSet(localPath string) *importSet {
+	return &importSet{
+		names:     make(map[string]string),
+		paths:     make(map[string]string),
+		localPath: localPath,
+	}
+}
+
+// add an import, preferring the given name. If the name is already taken
+// by another path, a numeric suffix is added. Returns the name to use
+func (s *importSet) add(name, importPath string) string {
+	if n, ok := s.paths[importPath]; ok {
+		return n
+	}
+	n := name
+	for i := 2; ; i++ {
+		if _, taken := s.names[n]; !taken {
+			break
+		}
+		n = name + strconv.Itoa(i)
+	}
+	s.names[n] = importPath
+	s.paths[importPath] = n
+	return n
+}
+
+// reserve the names of the imports the templates use, which can't
+// be renamed. a name or a path can't be imported twice
+func (s *importSet) addAll(imports map[string]string) error {
+	names := []string{}
+	for n := range imports {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p := imports[n]
+		if other, ok := s.names[n]; ok && other != p {
+			return fmt.Errorf("template imports: %s is both %q and %q", n, other, p)
+		}
+		if other, ok := s.paths[p]; ok && other != n {
+			return fmt.Errorf("template imports: %q is imported as both %s and %s", p, other, n)
+		}
+		s.names[n] = p
+		s.paths[p] = n
+	}
+	return nil
+}
+
+// return the name to qualify the package's objects with,
+// adding it to the set
+func (s *importSet) qualify(pkg *types.Package) string {
+	if pkg.Path() == s.localPath {
+		return ""
+	}
+	return s.add(pkg.Name(), pkg.Path())
+}
+
+// the imports as a map from name to path
+func (s *importSet) imports() map[string]string {
+	imps := make(map[string]string)
+	for n, p := range s.names {
+		imps[n] = p
+	}
+	return imps
+}
+
+// whether the import path is in the standard library,
+// going by the first element of the path not having a dot
+func isStdlib(importPath string) bool {
+	first := strings.SplitN(importPath, "/", 2)[0]
+	return !strings.Contains(first, ".")
+}
+
+// write the import block: the standard library first and
+// then everything else, each group sorted by path
+func writeImports(buf *bytes.Buffer, imports map[string]string) {
+	if len(imports) == 0 {
+		return
+	}
+	std, other := []string{}, []string{}
+	names := make(map[string]string)
+	for n, p := range imports {
+		names[p] = n
+		if isStdlib(p) {
+			std = append(std, p)
+		} else {
+			other = append(other, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	fmt.Fprintln(buf, "import (")
+	for i, group := range [][]string{std, other} {
+		if i > 0 && len(std) > 0 && len(other) > 0 {
+			fmt.Fprintln(buf, "")
+		}
+		for _, p := range group {
+			if n := names[p]; n != path.Base(p) {
+				fmt.Fprintf(buf, "\t%s %q\n", n, p)
+			} else {
+				fmt.Fprintf(buf, "\t%q\n", p)
+			}
+		}
+	}
+	fmt.Fprintln(buf, ")")
+	fmt.Fprintln(buf, "")
+}
+
+// the imports that are referenced by the go source of the file's body.
+// a package is referenced by a selector on a name that isn't declared
+// in the file. if the body doesn't parse, all the imports are kept,
+// and the error is left to be reported with the rest of the file
+func usedImports(imports map[string]string, body []byte) map[string]string {
+	src := append([]byte("package p\n"), body...)
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "", src, 0)
+	if err != nil {
+		return imports
+	}
+	refs := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				refs[x.Name] = true
+			}
+		}
+		return true
+	})
+
+	used := make(map[string]string)
+	for n, p := range imports {
+		if refs[n] || n == "_" || n == "." {
+			used[n] = p
+		}
+	}
+	return used
+}
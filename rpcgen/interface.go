@@ -0,0 +1,155 @@
+package rpcgen
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// the base interface declared with rpc-gen:define-interface
+
+// parse the base interface's declaration, in the context of the
+// package clause and imports of the file it was declared in
+func (rg *RpcGen) parseBaseInterface(fset *gotoken.FileSet) (*ast.File, *ast.InterfaceType, error) {
+	src := "package " + rg.ifaceFile.Name.Name + "\n\n"
+	for _, d := range rg.ifaceFile.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == gotoken.IMPORT {
+			for _, spec := range gd.Specs {
+				s := spec.(*ast.ImportSpec)
+				name := ""
+				if s.Name != nil {
+					name = s.Name.Name + " "
+				}
+				src += "import " + name + s.Path.Value + "\n"
+			}
+		}
+	}
+	src += "\n" + rg.ifaceDef + "\n"
+
+	filename := "rpc-gen:define-interface " + rg.ifaceName
+	file, err := goparser.ParseFile(fset, filename, src, goparser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != gotoken.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if it, ok := ts.Type.(*ast.InterfaceType); ok && ts.Name.Name == rg.ifaceName {
+				return file, it, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("rpc-gen:define-interface %s doesn't declare interface %s", rg.ifaceName, rg.ifaceName)
+}
+
+// names of the methods declared directly in the base interface
+func baseMethodNames(it *ast.InterfaceType) []string {
+	names := []string{}
+	for _, m := range it.Methods.List {
+		for _, n := range m.Names {
+			names = append(names, n.Name)
+		}
+	}
+	return names
+}
+
+// add the imports used by the base interface to imps
+func baseImports(file *ast.File, it *ast.InterfaceType, imps *importSet) error {
+	fileImps := make(map[string]string)
+	for _, s := range file.Imports {
+		p, _ := strconv.Unquote(s.Path.Value)
+		name := path.Base(p)
+		if s.Name != nil {
+			name = s.Name.Name
+		}
+		fileImps[name] = p
+	}
+
+	var err error
+	ast.Inspect(it, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		p, ok := fileImps[x.Name]
+		if !ok {
+			err = fmt.Errorf("rpc-gen:define-interface: unknown package %s", x.Name)
+			return false
+		}
+		if name := imps.add(x.Name, p); name != x.Name {
+			err = fmt.Errorf("rpc-gen:define-interface: package %s (%s) conflicts with another import", x.Name, p)
+			return false
+		}
+		return false
+	})
+	return err
+}
+
+// check each of the client types implements the methods of the base interface,
+// and return the interface. the package in the current dir is type checked without
+// the file being generated, and with the base interface declared. type errors are
+// otherwise ignored, since the package may not compile until the generated code is written
+func checkImplements(fset *gotoken.FileSet, imp *srcImporter, pkg *ast.Package, pkgPath, outFile string, defFile *ast.File, ifaceName string, clientTypes []string) (*types.Interface, error) {
+	names := []string{}
+	for n := range pkg.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	files := []*ast.File{defFile}
+	for _, n := range names {
+		if filepath.Base(n) == filepath.Base(outFile) || strings.HasSuffix(n, "_test.go") {
+			continue
+		}
+		files = append(files, pkg.Files[n])
+	}
+
+	conf := types.Config{
+		Importer:         imp,
+		IgnoreFuncBodies: true,
+		Error:            func(error) {},
+	}
+	tpkg, _ := conf.Check(pkgPath, fset, files, nil)
+
+	obj, ok := tpkg.Scope().Lookup(ifaceName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found", ifaceName)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", ifaceName)
+	}
+	for _, clientType := range clientTypes {
+		name := strings.TrimPrefix(clientType, "*")
+		tobj, ok := tpkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		var typ types.Type = tobj.Type()
+		if strings.HasPrefix(clientType, "*") {
+			typ = types.NewPointer(typ)
+		}
+		if m, wrongType := types.MissingMethod(typ, iface, true); m != nil {
+			if wrongType {
+				return nil, fmt.Errorf("%s does not implement %s: wrong type for method %s", clientType, ifaceName, m.Name())
+			}
+			return nil, fmt.Errorf("%s does not implement %s: missing method %s", clientType, ifaceName, m.Name())
+		}
+	}
+	return iface, nil
+}
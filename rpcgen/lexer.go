@@ -0,0 +1,287 @@
+package rpcgen
+
+// This lexer is heavily inspired by Rob Pike's "Lexical Scanning in Go"
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type lexStateFunc func(*lexer) lexStateFunc
+
+// a token
+type token struct {
+	typ tokenType
+	val string
+
+	loc location
+}
+
+// the lexer object
+type lexer struct {
+	input  string // input string to lex
+	length int    // length of the input string
+	pos    int    // current pos
+	start  int    // start of current token
+	width  int    // width in bytes of the last rune read
+	open   int    // pos of the last {{
+
+	base    location // location of the start of the input in its file
+	lastPos int      // the last pos located, and its location
+	lastLoc location
+
+	tokens []token // the tokens lexed so far
+
+	temp string // a place to hold eg. commands
+}
+
+// location for error reporting. columns count runes, from 1
+type location struct {
+	file string
+	line int
+	col  int
+}
+
+func (loc location) String() string {
+	return fmt.Sprintf("%s:%d:%d", loc.file, loc.line, loc.col)
+}
+
+// the location just after the text, if it starts at loc
+func (loc location) advance(text string) location {
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		loc.line += strings.Count(text, "\n")
+		loc.col = 1
+		text = text[i+1:]
+	}
+	loc.col += utf8.RuneCountInString(text)
+	return loc
+}
+
+// Lex the whole input, returning the lexer with its tokens,
+// the last of which is an EOF.
+// base is the location of the input in its file
+func Lex(input string, base location) *lexer {
+	l := &lexer{
+		input:   input,
+		length:  len(input),
+		pos:     0,
+		base:    base,
+		lastLoc: base,
+		tokens:  []token{},
+	}
+	l.run()
+	return l
+}
+
+// emit an error at the start of the current token
+func (l *lexer) Error(s string) lexStateFunc {
+	return l.ErrorAt(l.start, s)
+}
+
+// emit an error at the given pos
+func (l *lexer) ErrorAt(pos int, s string) lexStateFunc {
+	return func(l *lexer) lexStateFunc {
+		l.tokens = append(l.tokens, token{typ: tokenErrTy, val: s, loc: l.locate(pos)})
+		return nil
+	}
+}
+
+// the location of a pos in the input. tokens are located in order,
+// so carry on from the last one rather than starting again
+func (l *lexer) locate(pos int) location {
+	if pos < l.lastPos {
+		return l.base.advance(l.input[:pos])
+	}
+	l.lastLoc = l.lastLoc.advance(l.input[l.lastPos:pos])
+	l.lastPos = pos
+	return l.lastLoc
+}
+
+// Return the tokens
+func (l *lexer) Tokens() []token {
+	return l.tokens
+}
+
+// Run the lexer
+func (l *lexer) run() {
+	for state := lexStateStart; state != nil; state = state(l) {
+	}
+	l.emit(tokenEOFTy)
+}
+
+const eof = -1
+
+// Return the next rune in the input, or eof.
+// invalid utf8 comes back as utf8.RuneError
+func (l *lexer) next() rune {
+	if l.pos >= l.length {
+		l.width = 0
+		return eof
+	}
+	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.width = w
+	l.pos += w
+	return r
+}
+
+// backup a step (a no-op after reaching the end)
+func (l *lexer) backup() {
+	l.pos -= l.width
+}
+
+// peek ahead a rune without consuming
+func (l *lexer) peek() rune {
+	r := l.next()
+	l.backup()
+	return r
+}
+
+// consume a token and add it to the list
+func (l *lexer) emit(ty tokenType) {
+	l.tokens = append(l.tokens, token{
+		typ: ty,
+		val: l.input[l.start:l.pos],
+		loc: l.locate(l.start),
+	})
+	l.start = l.pos
+}
+
+func (l *lexer) accept(valid func(rune) bool) bool {
+	if valid(l.next()) {
+		return true
+	}
+	l.backup()
+	return false
+}
+
+func (l *lexer) acceptRun(valid func(rune) bool) bool {
+	i := 0
+	for valid(l.next()) {
+		i += 1
+	}
+	l.backup()
+	return i > 0
+}
+
+// Starting state
+func lexStateStart(l *lexer) lexStateFunc {
+	// skip to the next {{
+	if i := strings.Index(l.input[l.pos:], tokenLeftBraces); i >= 0 {
+		l.pos += i
+		if i := invalidUTF8(l.input[l.start:l.pos]); i >= 0 {
+			return l.ErrorAt(l.start+i, "Invalid UTF-8")
+		}
+		if l.pos > l.start {
+			l.emit(tokenStringTy)
+		}
+		return lexStateLeftBraces // Next state.
+	}
+	// Correctly reached EOF.
+	l.pos = l.length
+	if i := invalidUTF8(l.input[l.start:]); i >= 0 {
+		return l.ErrorAt(l.start+i, "Invalid UTF-8")
+	}
+	if l.pos > l.start {
+		l.emit(tokenStringTy)
+	}
+	return nil // Stop the run loop.
+}
+
+// the offset of the first invalid utf8 in s, or -1
+func invalidUTF8(s string) int {
+	if utf8.ValidString(s) {
+		return -1
+	}
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, w := utf8.DecodeRuneInString(s[i:]); w == 1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// identifiers and references, eg. args.0.def or lowername
+func isIdentChar(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Inside {{ }}: identifiers, string literals, parens and commas.
+// Spaces separate tokens but aren't emitted
+func lexStateExpr(l *lexer) lexStateFunc {
+	for {
+		if strings.HasPrefix(l.input[l.pos:], tokenRightBraces) {
+			return lexStateRightBraces
+		}
+		switch r := l.next(); {
+		case r == eof:
+			return l.ErrorAt(l.open, "Unclosed "+tokenLeftBraces)
+		case r == utf8.RuneError && l.width == 1:
+			return l.Error("Invalid UTF-8")
+		case isSpace(r):
+			l.start = l.pos
+		case r == tokenLeftBrace:
+			l.emit(tokenLeftBraceTy)
+		case r == tokenRightBrace:
+			l.emit(tokenRightBraceTy)
+		case r == tokenComma:
+			l.emit(tokenCommaTy)
+		case r == '"' || r == '`':
+			return lexStateQuote
+		case isIdentChar(r):
+			l.acceptRun(isIdentChar)
+			l.emit(tokenStringTy)
+		default:
+			return l.Error(fmt.Sprintf("Invalid char: %q", r))
+		}
+	}
+}
+
+// On {{
+func lexStateLeftBraces(l *lexer) lexStateFunc {
+	l.open = l.pos
+	l.pos += len(tokenLeftBraces)
+	l.emit(tokenLeftBracesTy)
+	return lexStateExpr
+}
+
+// On }}
+func lexStateRightBraces(l *lexer) lexStateFunc {
+	l.pos += len(tokenRightBraces)
+	l.emit(tokenRightBracesTy)
+	return lexStateStart
+}
+
+// a string literal. it's emitted with its quotes,
+// the parser checks and keeps them
+func lexStateQuote(l *lexer) lexStateFunc {
+	quote, _ := utf8.DecodeRuneInString(l.input[l.start:])
+	for {
+		switch r := l.next(); r {
+		case eof:
+			return l.Error("Unterminated string literal")
+		case utf8.RuneError:
+			if l.width == 1 {
+				return l.ErrorAt(l.pos-1, "Invalid UTF-8")
+			}
+		case '\n':
+			if quote == '"' {
+				return l.Error("Unterminated string literal")
+			}
+		case '\\':
+			if quote == '"' {
+				l.next()
+			}
+		case quote:
+			l.emit(tokenQuoteTy)
+			return lexStateExpr
+		}
+	}
+}
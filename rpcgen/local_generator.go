@@ -0,0 +1,131 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// generate an in-process client that calls the core functions directly
+
+// the fields of the local client mustn't clash with its methods
+func checkLocalNames(local string, service bool, funcs []*Func) error {
+	fields := []string{"RoundTrip"}
+	if service {
+		fields = append(fields, "Service")
+	}
+	for _, f := range funcs {
+		for _, field := range fields {
+			if f.Name == field {
+				return fmt.Errorf("can't generate %s: method %s clashes with its field %s", local, f.Name, field)
+			}
+		}
+	}
+	return nil
+}
+
+// write the local client type and its methods. core is what the
+// functions are called on: the core package's name, or the service.
+// with RoundTrip set, each arg is copied through it before the call
+// and each result after, so they're encoded and decoded as they would
+// be on the wire. the methods of the base interface are left to the user
+func writeLocal(buf *bytes.Buffer, local, iface, core, service string, funcs []*Func) {
+	fmt.Fprintf(buf, "// %s is a %s that calls the core functions directly, in process.\n", local, iface)
+	fmt.Fprintln(buf, "// If RoundTrip is set, each arg and result is copied through it, eg. encoded")
+	fmt.Fprintln(buf, "// and decoded with the server's codec, so serialization bugs still show up.")
+	fmt.Fprintln(buf, "// A method without an error to return panics if RoundTrip fails.")
+	fmt.Fprintf(buf, "type %s struct {\n", local)
+	if service != "" {
+		fmt.Fprintf(buf, "\tService %s\n", service)
+	}
+	fmt.Fprintln(buf, "\tRoundTrip func(from, to interface{}) error // decode into to what from encodes to")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "var _ %s = (*%s)(nil)\n", iface, local)
+	fmt.Fprintln(buf, "")
+	if service != "" {
+		core = "_c.Service"
+	}
+	for _, f := range funcs {
+		writeLocalMethod(buf, local, core, f)
+	}
+}
+
+// write the method calling the function, through RoundTrip if it's set.
+// the params are renamed _ctx, _p0, _p1, .. so they can't shadow core or an
+// import, and the results are named _r0, _r1, .. so a failed RoundTrip can
+// return its error without spelling out the zero values of the others
+func writeLocalMethod(buf *bytes.Buffer, local, core string, f *Func) {
+	results := []string{}
+	rets := []string{}
+	errRet := ""
+	for i, r := range f.ReturnTypes {
+		ret := fmt.Sprintf("_r%d", i)
+		results = append(results, ret+" "+r)
+		rets = append(rets, ret)
+		if r == "error" && i == len(f.ReturnTypes)-1 {
+			errRet = ret
+		}
+	}
+	// a RoundTrip error is returned if the function returns one
+	fail := func(indent, err string) {
+		if errRet != "" {
+			fmt.Fprintf(buf, "%s%s = %s\n", indent, errRet, err)
+			fmt.Fprintf(buf, "%sreturn\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%spanic(%s)\n", indent, err)
+		}
+	}
+
+	params, args := f.renamedParams()
+	fmt.Fprintf(buf, "func (_c *%s) %s(%s) (%s) {\n", local, f.Name, strings.Join(params, ", "), strings.Join(results, ", "))
+	if len(f.ArgNames) > 0 {
+		fmt.Fprintln(buf, "\tif _c.RoundTrip != nil {")
+		for i, t := range f.ArgTypes {
+			if strings.HasPrefix(t, "...") {
+				t = "[]" + strings.TrimPrefix(t, "...")
+			}
+			fmt.Fprintf(buf, "\t\tvar _a%d %s\n", i, t)
+			fmt.Fprintf(buf, "\t\tif err := _c.RoundTrip(_p%d, &_a%d); err != nil {\n", i, i)
+			fail("\t\t\t", "err")
+			fmt.Fprintln(buf, "\t\t}")
+			fmt.Fprintf(buf, "\t\t_p%d = _a%d\n", i, i)
+		}
+		fmt.Fprintln(buf, "\t}")
+	}
+	call := fmt.Sprintf("%s.%s(%s)", core, f.Name, strings.Join(args, ", "))
+	if len(rets) == 0 {
+		fmt.Fprintf(buf, "\t%s\n", call)
+		fmt.Fprintln(buf, "}")
+		fmt.Fprintln(buf, "")
+		return
+	}
+	fmt.Fprintf(buf, "\t%s = %s\n", strings.Join(rets, ", "), call)
+
+	// the results, unless the function failed
+	cond := "_c.RoundTrip != nil"
+	if errRet != "" {
+		cond += " && " + errRet + " == nil"
+	}
+	roundTrip := []int{}
+	for i, ret := range rets {
+		if ret != errRet {
+			roundTrip = append(roundTrip, i)
+		}
+	}
+	if len(roundTrip) > 0 {
+		fmt.Fprintf(buf, "\tif %s {\n", cond)
+		for _, i := range roundTrip {
+			fmt.Fprintf(buf, "\t\tvar _v%d %s\n", i, f.ReturnTypes[i])
+			fmt.Fprintf(buf, "\t\tif err := _c.RoundTrip(_r%d, &_v%d); err != nil {\n", i, i)
+			fail("\t\t\t", "err")
+			fmt.Fprintln(buf, "\t\t}")
+			fmt.Fprintf(buf, "\t\t_r%d = _v%d\n", i, i)
+		}
+		fmt.Fprintln(buf, "\t}")
+	}
+	fmt.Fprintln(buf, "\treturn")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+}
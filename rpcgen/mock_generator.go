@@ -0,0 +1,248 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// generate a mock implementation of the interface, for tests
+
+// a method of the mock. the method renames its params _ctx, _p0, _p1, ..
+// so they can't shadow an import, but the func keeps their names
+type mockMethod struct {
+	name    string
+	params  []string // name and type of each param, as declared
+	renamed []string // the params as the method declares them
+	args    []string // the renamed params as passed on to the method's func
+	record  []string // the renamed params recorded with the call (not the context)
+	results []string // return types
+}
+
+// the mock method for a core function
+func funcMockMethod(f *Func) mockMethod {
+	m := mockMethod{name: f.Name, results: f.ReturnTypes}
+	if f.CtxName != "" {
+		m.params = append(m.params, f.CtxName+" "+f.CtxType)
+	}
+	for i, n := range f.ArgNames {
+		m.params = append(m.params, n+" "+f.ArgTypes[i])
+		m.record = append(m.record, fmt.Sprintf("_p%d", i))
+	}
+	m.renamed, m.args = f.renamedParams()
+	return m
+}
+
+// the mock methods for the methods of the base interface (including those
+// it embeds), which aren't core functions and so needn't go over the wire
+func baseMockMethods(iface *types.Interface, q types.Qualifier) []mockMethod {
+	methods := []mockMethod{}
+	for i := 0; i < iface.NumMethods(); i++ {
+		fn := iface.Method(i)
+		sig := fn.Type().(*types.Signature)
+		m := mockMethod{name: fn.Name()}
+		params := sig.Params()
+		for j := 0; j < params.Len(); j++ {
+			n := params.At(j).Name()
+			if n == "" || n == "_" {
+				n = "arg" + strconv.Itoa(j)
+			}
+			t := types.TypeString(params.At(j).Type(), q)
+			p := fmt.Sprintf("_p%d", j)
+			arg := p
+			if sig.Variadic() && j == params.Len()-1 {
+				t = "..." + types.TypeString(params.At(j).Type().(*types.Slice).Elem(), q)
+				arg += "..."
+			}
+			m.params = append(m.params, n+" "+t)
+			m.renamed = append(m.renamed, p+" "+t)
+			m.args = append(m.args, arg)
+			m.record = append(m.record, p)
+		}
+		results := sig.Results()
+		for j := 0; j < results.Len(); j++ {
+			m.results = append(m.results, types.TypeString(results.At(j).Type(), q))
+		}
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// the mock's own methods and those it generates for each method
+// mustn't clash with the methods of the interface
+func checkMockNames(mock string, methods []mockMethod) error {
+	members := make(map[string]string)
+	for _, n := range []string{"Calls", "CallCount", "AssertCallCount", "Reset", "record", "setReturns"} {
+		members[n] = "its own method " + n
+	}
+	for _, m := range methods {
+		members[m.name+"Func"] = "the func for " + m.name
+		if len(m.results) > 0 {
+			members[m.name+"Returns"] = "the Returns method for " + m.name
+		}
+	}
+	for _, m := range methods {
+		if desc, ok := members[m.name]; ok {
+			return fmt.Errorf("can't generate %s: method %s clashes with %s", mock, m.name, desc)
+		}
+	}
+	return nil
+}
+
+// write the mock type implementing the interface. each method records
+// its call, then calls <Method>Func if it's set, or returns the values
+// set with <Method>Returns (zero values if there are none).
+// syncPkg is the name sync is imported as
+func writeMock(buf *bytes.Buffer, mock, iface, syncPkg string, methods []mockMethod) {
+	fmt.Fprintf(buf, "// %s is a %s for tests. Each method records its call, then calls the\n", mock, iface)
+	fmt.Fprintln(buf, "// method's func (<Method>Func) if it's set, or returns the values set")
+	fmt.Fprintln(buf, "// with <Method>Returns, or else zero values.")
+	fmt.Fprintf(buf, "type %s struct {\n", mock)
+	for _, m := range methods {
+		fmt.Fprintf(buf, "\t%sFunc func(%s) (%s)\n", m.name, strings.Join(m.params, ", "), strings.Join(m.results, ", "))
+	}
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "\tmu      %s.Mutex\n", syncPkg)
+	fmt.Fprintf(buf, "\tcalls   []%sCall\n", mock)
+	fmt.Fprintln(buf, "\treturns map[string][]interface{}")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "var _ %s = (*%s)(nil)\n", iface, mock)
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "// %sCall is a call made to a %s, with the args the method was called with\n", mock, mock)
+	fmt.Fprintln(buf, "// (without any context).")
+	fmt.Fprintf(buf, "type %sCall struct {\n", mock)
+	fmt.Fprintln(buf, "\tMethod string")
+	fmt.Fprintln(buf, "\tArgs   []interface{}")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "// %sT is the part of *testing.T the %s's assertions use.\n", mock, mock)
+	fmt.Fprintf(buf, "type %sT interface {\n", mock)
+	fmt.Fprintln(buf, "\tHelper()")
+	fmt.Fprintln(buf, "\tErrorf(format string, args ...interface{})")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+
+	for _, m := range methods {
+		writeMockMethod(buf, mock, m)
+	}
+
+	fmt.Fprintln(buf, "// record the call, and return the values set for the method, if any")
+	fmt.Fprintf(buf, "func (_m *%s) record(method string, args ...interface{}) []interface{} {\n", mock)
+	fmt.Fprintln(buf, "\t_m.mu.Lock()")
+	fmt.Fprintln(buf, "\tdefer _m.mu.Unlock()")
+	fmt.Fprintf(buf, "\t_m.calls = append(_m.calls, %sCall{Method: method, Args: args})\n", mock)
+	fmt.Fprintln(buf, "\treturn _m.returns[method]")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintf(buf, "func (_m *%s) setReturns(method string, values ...interface{}) {\n", mock)
+	fmt.Fprintln(buf, "\t_m.mu.Lock()")
+	fmt.Fprintln(buf, "\tdefer _m.mu.Unlock()")
+	fmt.Fprintln(buf, "\tif _m.returns == nil {")
+	fmt.Fprintln(buf, "\t\t_m.returns = make(map[string][]interface{})")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\t_m.returns[method] = values")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "// Calls returns the calls made to the method, in order,")
+	fmt.Fprintln(buf, "// or all the calls made if method is \"\".")
+	fmt.Fprintf(buf, "func (_m *%s) Calls(method string) []%sCall {\n", mock, mock)
+	fmt.Fprintln(buf, "\t_m.mu.Lock()")
+	fmt.Fprintln(buf, "\tdefer _m.mu.Unlock()")
+	fmt.Fprintf(buf, "\tcalls := []%sCall{}\n", mock)
+	fmt.Fprintln(buf, "\tfor _, c := range _m.calls {")
+	fmt.Fprintln(buf, "\t\tif method == \"\" || c.Method == method {")
+	fmt.Fprintln(buf, "\t\t\tcalls = append(calls, c)")
+	fmt.Fprintln(buf, "\t\t}")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\treturn calls")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "// CallCount returns the number of calls made to the method.")
+	fmt.Fprintf(buf, "func (_m *%s) CallCount(method string) int {\n", mock)
+	fmt.Fprintln(buf, "\treturn len(_m.Calls(method))")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "// AssertCallCount reports an error to t unless the method was called n times.")
+	fmt.Fprintf(buf, "func (_m *%s) AssertCallCount(t %sT, method string, n int) bool {\n", mock, mock)
+	fmt.Fprintln(buf, "\tt.Helper()")
+	fmt.Fprintln(buf, "\tswitch method {")
+	names := []string{}
+	for _, m := range methods {
+		names = append(names, strconv.Quote(m.name))
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(buf, "\tcase %s:\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintln(buf, "\tdefault:")
+	fmt.Fprintf(buf, "\t\tt.Errorf(\"%s has no method %%s\", method)\n", mock)
+	fmt.Fprintln(buf, "\t\treturn false")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\tif got := _m.CallCount(method); got != n {")
+	fmt.Fprintf(buf, "\t\tt.Errorf(\"%s.%%s called %%d times, expected %%d\", method, got, n)\n", mock)
+	fmt.Fprintln(buf, "\t\treturn false")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\treturn true")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "// Reset forgets the calls made so far.")
+	fmt.Fprintf(buf, "func (_m *%s) Reset() {\n", mock)
+	fmt.Fprintln(buf, "\t_m.mu.Lock()")
+	fmt.Fprintln(buf, "\tdefer _m.mu.Unlock()")
+	fmt.Fprintln(buf, "\t_m.calls = nil")
+	fmt.Fprintln(buf, "}")
+}
+
+// write the method, and its Returns method
+func writeMockMethod(buf *bytes.Buffer, mock string, m mockMethod) {
+	params := strings.Join(m.renamed, ", ")
+	results := strings.Join(m.results, ", ")
+	record := append([]string{strconv.Quote(m.name)}, m.record...)
+
+	fmt.Fprintf(buf, "func (_m *%s) %s(%s) (%s) {\n", mock, m.name, params, results)
+	ret := "_ = "
+	if len(m.results) > 0 {
+		ret = "_ret := "
+	}
+	fmt.Fprintf(buf, "\t%s_m.record(%s)\n", ret, strings.Join(record, ", "))
+	fmt.Fprintf(buf, "\tif _m.%sFunc != nil {\n", m.name)
+	if len(m.results) > 0 {
+		fmt.Fprintf(buf, "\t\treturn _m.%sFunc(%s)\n", m.name, strings.Join(m.args, ", "))
+	} else {
+		fmt.Fprintf(buf, "\t\t_m.%sFunc(%s)\n", m.name, strings.Join(m.args, ", "))
+	}
+	fmt.Fprintln(buf, "\t}")
+	if len(m.results) > 0 {
+		rs := []string{}
+		for i, r := range m.results {
+			fmt.Fprintf(buf, "\tvar _r%d %s\n", i, r)
+			rs = append(rs, fmt.Sprintf("_r%d", i))
+		}
+		fmt.Fprintln(buf, "\tif _ret != nil {")
+		for i, r := range m.results {
+			fmt.Fprintf(buf, "\t\t_r%d, _ = _ret[%d].(%s)\n", i, i, r)
+		}
+		fmt.Fprintln(buf, "\t}")
+		fmt.Fprintf(buf, "\treturn %s\n", strings.Join(rs, ", "))
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+
+	if len(m.results) == 0 {
+		return
+	}
+	rets := []string{}
+	vals := []string{strconv.Quote(m.name)}
+	for i, r := range m.results {
+		rets = append(rets, fmt.Sprintf("r%d %s", i, r))
+		vals = append(vals, fmt.Sprintf("r%d", i))
+	}
+	fmt.Fprintf(buf, "// %sReturns sets the values %s returns when %sFunc isn't set.\n", m.name, m.name, m.name)
+	fmt.Fprintf(buf, "func (_m *%s) %sReturns(%s) {\n", mock, m.name, strings.Join(rets, ", "))
+	fmt.Fprintf(buf, "\t_m.setReturns(%s)\n", strings.Join(vals, ", "))
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+}
@@ -0,0 +1,292 @@
+package rpcgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type parseStateFunc func(p *parser) parseStateFunc
+
+type parser struct {
+	tokens    []token
+	i         int // index of the next token
+	last      token
+	peekCount int // 1 if we've peeked
+
+	nodes  []node  // the parsed template
+	blocks []*node // open if and range blocks, innermost last
+
+	err error // the first error, if any
+}
+
+func (p *parser) results() []node {
+	return p.nodes
+}
+
+type nodeType int
+
+const (
+	nodeText  nodeType = iota // surrounding go code
+	nodeExpr                  // {{expr}}
+	nodeIf                    // {{if expr}} body {{else}} elseBody {{end}}
+	nodeRange                 // {{range args}} body {{else}} elseBody {{end}}
+)
+
+// a node of the parsed template
+type node struct {
+	typ      nodeType
+	text     string
+	job      Job // the expression, or the block's condition or what it ranges over
+	body     []node
+	elseBody []node
+	inElse   bool // the parser is past the {{else}}
+}
+
+// add a node to the innermost open block, or the template itself
+func (p *parser) add(n node) {
+	if len(p.blocks) == 0 {
+		p.nodes = append(p.nodes, n)
+		return
+	}
+	b := p.blocks[len(p.blocks)-1]
+	if b.inElse {
+		b.elseBody = append(b.elseBody, n)
+	} else {
+		b.body = append(b.body, n)
+	}
+}
+
+// an expression inside {{ }}. either a keyword or reference (eg. args.0),
+// a string literal, or a call of a helper or set on further expressions:
+//
+//	{{encode(args.0, "hex")}}
+//	{{encode args.0 "hex"}}
+type Job struct {
+	ident string
+	lit   string // quoted string literal, if there's no ident
+	args  []Job
+
+	loc location
+}
+
+// an error at a location in a template
+type templateError struct {
+	loc location
+	msg string
+}
+
+func (e *templateError) Error() string {
+	return e.loc.String() + ": " + e.msg
+}
+
+// parse the template. base is its location in its file
+func Parser(input string, base location) *parser {
+	l := Lex(input, base)
+	p := &parser{
+		tokens: l.Tokens(),
+		nodes:  []node{},
+	}
+	return p
+}
+
+func (p *parser) next() token {
+	if p.peekCount == 1 {
+		p.peekCount = 0
+		return p.last
+
+	}
+	// the last token is always EOF, so keep returning it
+	p.last = p.tokens[p.i]
+	if p.i < len(p.tokens)-1 {
+		p.i++
+	}
+	return p.last
+}
+
+func (p *parser) peek() token {
+	if p.peekCount == 1 {
+		return p.last
+	}
+	p.next()
+	p.peekCount = 1
+	return p.last
+}
+
+func (p *parser) backup() {
+	p.peekCount = 1
+}
+
+func (p *parser) run() error {
+	for state := parseStateStart; state != nil; state = state(p) {
+	}
+	return p.err
+}
+
+// return a parseStateFunc that records the error at the last token
+// and triggers exit (returns nil)
+func (p *parser) Error(s string) parseStateFunc {
+	return p.ErrorAt(p.last.loc, s)
+}
+
+// return a parseStateFunc that records the error at loc and triggers exit
+func (p *parser) ErrorAt(loc location, s string) parseStateFunc {
+	return func(pp *parser) parseStateFunc {
+		pp.err = &templateError{loc: loc, msg: s}
+		return nil
+	}
+}
+
+func parseStateStart(p *parser) parseStateFunc {
+	t := p.next()
+	// scan past spaces, new lines, and comments
+	switch t.typ {
+	case tokenErrTy:
+		return p.Error(t.val)
+	case tokenEOFTy:
+		if len(p.blocks) > 0 {
+			b := p.blocks[len(p.blocks)-1]
+			return p.ErrorAt(b.job.loc, fmt.Sprintf("Missing {{end}} for {{%s}}", b.job.ident))
+		}
+		return nil
+	//case tokenSpaceTy:
+	//return parseStateStart
+	case tokenStringTy, tokenLeftBraceTy, tokenRightBraceTy, tokenSpaceTy,
+		tokenLeftCurlBraceTy, tokenRightCurlBraceTy:
+		// write the text into the buffer
+		p.add(node{typ: nodeText, text: t.val})
+		return parseStateStart
+	case tokenLeftBracesTy:
+		return parseStateExpr
+	}
+
+	return p.Error(fmt.Sprintf("Unknown expression while parsing: %s", t.val))
+}
+
+// An expr contains an identifier that indicates which registered go functions
+// need to be pasted in. It may have arguments itself.
+func parseStateExpr(p *parser) parseStateFunc {
+	job, err := p.parseExpr()
+	if err != nil {
+		return p.Error(err.Error())
+	}
+	if t := p.next(); t.typ == tokenErrTy {
+		return p.Error(t.val)
+	} else if t.typ != tokenRightBracesTy {
+		return p.Error(fmt.Sprintf("Expected %s, got %s", tokenRightBraces, t.desc()))
+	}
+	if err := p.addExpr(job); err != nil {
+		return p.ErrorAt(job.loc, err.Error())
+	}
+	return parseStateStart
+}
+
+// add the expression to the template, opening or closing blocks
+// for if, range, else and end
+func (p *parser) addExpr(job Job) error {
+	switch job.ident {
+	case "if", "range":
+		if len(job.args) == 0 {
+			return fmt.Errorf("{{%s}} expects an expression", job.ident)
+		}
+		// if isSlice args.0 is the same as if isSlice(args.0)
+		cond := job.args[0]
+		if len(job.args) > 1 {
+			if cond.ident == "" || len(cond.args) > 0 {
+				return fmt.Errorf("{{%s}} expects a single expression", job.ident)
+			}
+			cond.args = job.args[1:]
+		}
+		typ := nodeIf
+		if job.ident == "range" {
+			typ = nodeRange
+		}
+		p.blocks = append(p.blocks, &node{typ: typ, job: Job{ident: job.ident, args: []Job{cond}, loc: job.loc}})
+	case "else":
+		if len(p.blocks) == 0 {
+			return fmt.Errorf("{{else}} outside of {{if}} or {{range}}")
+		}
+		b := p.blocks[len(p.blocks)-1]
+		if b.inElse || len(job.args) > 0 {
+			return fmt.Errorf("Unexpected {{else}} in {{%s}}", b.job.ident)
+		}
+		b.inElse = true
+	case "end":
+		if len(p.blocks) == 0 || len(job.args) > 0 {
+			return fmt.Errorf("Unexpected {{end}}")
+		}
+		b := p.blocks[len(p.blocks)-1]
+		p.blocks = p.blocks[:len(p.blocks)-1]
+		p.add(*b)
+	default:
+		p.add(node{typ: nodeExpr, job: job})
+	}
+	return nil
+}
+
+// an operand, which may be followed by space separated args (eg. binaryWriter args)
+func (p *parser) parseExpr() (Job, error) {
+	job, err := p.parseOperand()
+	if err != nil {
+		return job, err
+	}
+	for t := p.peek(); t.typ == tokenStringTy || t.typ == tokenQuoteTy; t = p.peek() {
+		if job.ident == "" {
+			return job, fmt.Errorf("Can't call string literal %s", job.lit)
+		}
+		arg, err := p.parseOperand()
+		if err != nil {
+			return job, err
+		}
+		job.args = append(job.args, arg)
+	}
+	return job, nil
+}
+
+// a string literal, or an identifier with optional args in parens
+func (p *parser) parseOperand() (Job, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenErrTy:
+		return Job{}, errors.New(t.val)
+	case tokenQuoteTy:
+		if _, err := strconv.Unquote(t.val); err != nil {
+			return Job{}, fmt.Errorf("Invalid string literal %s", t.val)
+		}
+		return Job{lit: t.val, loc: t.loc}, nil
+	case tokenStringTy:
+		job := Job{ident: t.val, loc: t.loc}
+		if p.peek().typ == tokenLeftBraceTy {
+			p.next()
+			if err := p.parseArgs(&job); err != nil {
+				return job, err
+			}
+		}
+		return job, nil
+	}
+	return Job{}, fmt.Errorf("Unexpected %s in expression", t.desc())
+}
+
+// comma separated args, up to the closing paren
+func (p *parser) parseArgs(j *Job) error {
+	if p.peek().typ == tokenRightBraceTy {
+		p.next()
+		return nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return err
+		}
+		j.args = append(j.args, arg)
+		switch t := p.next(); t.typ {
+		case tokenErrTy:
+			return errors.New(t.val)
+		case tokenCommaTy:
+		case tokenRightBraceTy:
+			return nil
+		default:
+			return fmt.Errorf("Expected %c or %c in args to %s, got %s", tokenComma, tokenRightBrace, j.ident, t.desc())
+		}
+	}
+}
@@ -0,0 +1,973 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gotoken "go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//--------------------------------------------------------------------------------
+// generate source code from template
+
+// keywords available in templates
+var keywords = map[string]bool{
+	"name":      true,
+	"args":      true,
+	"response":  true,
+	"lowername": true,
+	"ctx":       true,
+	"client":    true,
+}
+
+// interpret/replace simple commands found in templates
+func (rg *RpcGen) compileJob(buf *bytes.Buffer, f Func, job Job) error {
+	if job.ident == "" {
+		// a string literal
+		buf.WriteString(job.lit)
+		return nil
+	}
+	if strings.HasPrefix(job.ident, ".") {
+		return rg.compileDot(buf, job)
+	}
+	argNames := f.ArgNames
+	argTypes := f.ArgTypes
+	argWireNames := f.ArgWireNames
+	retTypes := f.ReturnTypes
+	// ident is either a keyword or a variable name
+	spl := strings.Split(job.ident, ".")
+	ident := spl[0]
+	if keywords[ident] && len(job.args) > 0 {
+		return fmt.Errorf("%s doesn't take arguments", job.ident)
+	}
+	switch ident {
+	case "name":
+		fmt.Fprint(buf, f.Name)
+	case "args":
+		if len(spl) < 2 {
+			return fmt.Errorf("Expected args.def, args.ident, args.name or args.N, got %s", job.ident)
+		}
+		field := strings.Join(spl[1:], ".")
+		all := true
+		if i, err := strconv.Atoi(spl[1]); err == nil {
+			// a single arg, by default its name (args.N, args.N.def)
+			if i < 0 || i >= len(f.ArgNames) {
+				return fmt.Errorf("%s has no argument %s", f.Name, job.ident)
+			}
+			argNames = []string{f.ArgNames[i]}
+			argTypes = []string{f.ArgTypes[i]}
+			argWireNames = []string{f.ArgWireNames[i]}
+			all = false
+			field = "ident"
+			if len(spl) > 2 {
+				field = strings.Join(spl[2:], ".")
+			}
+		}
+		switch field {
+		case "def":
+			if all {
+				// the whole signature, including any context
+				fmt.Fprint(buf, f.argsDef())
+			} else {
+				fmt.Fprint(buf, joinArgTypes(argNames, argTypes))
+			}
+		case "ident":
+			if len(f.ArgNames) == 0 {
+				fmt.Fprintf(buf, "")
+			} else {
+				fmt.Fprint(buf, strings.Join(argNames, ", "))
+			}
+		case "name":
+			if len(f.ArgNames) == 0 {
+				fmt.Fprintf(buf, "nil")
+			} else {
+				quoted := make([]string, len(argWireNames))
+				for i, wn := range argWireNames {
+					quoted[i] = strconv.Quote(wn)
+				}
+				fmt.Fprint(buf, "[]string{"+strings.Join(quoted, ", ")+"}")
+			}
+		default:
+			return fmt.Errorf("Unknown field %s of args", field)
+		}
+	case "response":
+		if len(spl) > 1 {
+			field := spl[1]
+			if i, err := strconv.Atoi(field); err == nil {
+				if i < 0 || i >= len(f.ReturnTypes) {
+					return fmt.Errorf("%s has no return value %s", f.Name, job.ident)
+				}
+				retTypes = []string{f.ReturnTypes[i]}
+			}
+		}
+		fmt.Fprint(buf, strings.Join(retTypes, ", "))
+	case "lowername":
+		fmt.Fprint(buf, strconv.Quote(f.WireName))
+	case "client":
+		// the type being implemented
+		fmt.Fprint(buf, rg.clientType)
+	case "ctx":
+		// the function's context, or a fresh one if it doesn't take one
+		if f.CtxName != "" {
+			fmt.Fprint(buf, f.CtxName)
+		} else {
+			fmt.Fprint(buf, rg.imps.add("context", "context")+".Background()")
+		}
+	default:
+		// check if the ident is registered
+		// and if so call the function
+		if def, ok := rg.funcdefs[job.ident]; ok {
+			ops, err := rg.evalArgs(f, job.args)
+			if err != nil {
+				return argsError(job, err)
+			}
+			call, err := defToCall(def, f, ops)
+			if err != nil {
+				// at the call, which may be inside another
+				return locateError(job.loc, f, err)
+			}
+			buf.WriteString(call)
+		} else if set, ok := rg.sets[ident]; ok {
+			// a serialization routine for particular types
+			// (set, set.encode or set.decode)
+			ops, err := rg.evalArgs(f, job.args)
+			if err != nil {
+				return argsError(job, err)
+			}
+			var call string
+			switch field := strings.Join(spl[1:], "."); field {
+			case "", "encode":
+				call, err = setToEncodeCalls(set, ops)
+			case "decode":
+				call, err = setToDecodeCall(set, ops)
+			default:
+				err = fmt.Errorf("Unknown routine %s for set %s", field, ident)
+			}
+			if err != nil {
+				return err
+			}
+			buf.WriteString(call)
+		} else {
+			return fmt.Errorf("Unknown identifier %s", job.ident)
+		}
+	}
+	return nil
+}
+
+// evaluate the args of a call to go expressions.
+// args and args.N refer to the function's args and response.N to one of its
+// return types. keywords and calls are compiled as they would be in the template,
+// and anything else is passed through as go code (eg. a variable in the template)
+func (rg *RpcGen) evalArgs(f Func, jobs []Job) ([]operand, error) {
+	ops := []operand{}
+	for _, j := range jobs {
+		spl := strings.Split(j.ident, ".")
+		switch {
+		case j.ident == "":
+			ops = append(ops, operand{src: j.lit, typ: "string"})
+		case j.ident == "." && len(j.args) == 0:
+			// the arg or return value being ranged over
+			if rg.dot == nil {
+				return nil, fmt.Errorf(". outside of {{range}}")
+			}
+			ops = append(ops, rg.dot.operand())
+		case strings.HasPrefix(j.ident, "."):
+			b := new(bytes.Buffer)
+			if err := rg.compileDot(b, j); err != nil {
+				return nil, err
+			}
+			ops = append(ops, operand{src: b.String()})
+		case len(j.args) == 0 && spl[0] == "args" && (len(spl) == 1 || isIndex(spl[1])):
+			refs, err := resolveArgRef(f, j.ident)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, refs...)
+		case len(j.args) == 0 && spl[0] == "response" && len(spl) > 1 && isIndex(spl[1]):
+			ref, err := resolveResponseRef(f, j.ident)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, ref)
+		case keywords[spl[0]] || rg.funcdefs[j.ident] != nil || rg.sets[spl[0]] != nil:
+			b := new(bytes.Buffer)
+			if err := rg.compileJob(b, f, j); err != nil {
+				return nil, err
+			}
+			ops = append(ops, operand{src: b.String()})
+		case len(j.args) > 0:
+			return nil, fmt.Errorf("Unknown helper %s", j.ident)
+		default:
+			ops = append(ops, operand{src: j.ident})
+		}
+	}
+	return ops, nil
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+//--------------------------------------------------------------------------------
+// {{if}} and {{range}} blocks
+
+// an arg or return value being ranged over, referred to as .
+type rangeElem struct {
+	ref      string // "args" or "response"
+	index    int
+	count    int    // number of elements in the range
+	name     string // name of the arg (empty for returns)
+	wireName string // name of the arg on the wire
+	typ      string // the type as printed in the generated code
+	gotype   types.Type
+}
+
+// the element as an operand to a helper or set
+func (e *rangeElem) operand() operand {
+	if e.ref == "args" {
+		return operand{src: e.name, typ: e.typ, gotype: e.gotype, ref: e.ref}
+	}
+	return operand{src: e.typ, typ: e.typ, gotype: e.gotype, ref: e.ref}
+}
+
+// the elements of args or response, for {{range}}
+func (rg *RpcGen) rangeElems(f Func, job Job) ([]rangeElem, error) {
+	elems := []rangeElem{}
+	switch {
+	case job.ident == "args" && len(job.args) == 0:
+		for i, n := range f.ArgNames {
+			elems = append(elems, rangeElem{
+				ref:      "args",
+				index:    i,
+				count:    len(f.ArgNames),
+				name:     n,
+				wireName: f.ArgWireNames[i],
+				typ:      f.ArgTypes[i],
+				gotype:   f.params[i],
+			})
+		}
+	case job.ident == "response" && len(job.args) == 0:
+		for i, t := range f.ReturnTypes {
+			elems = append(elems, rangeElem{
+				ref:    "response",
+				index:  i,
+				count:  len(f.ReturnTypes),
+				typ:    t,
+				gotype: f.results[i],
+			})
+		}
+	default:
+		return nil, fmt.Errorf("Can't range over %s, expected args or response", jobString(job))
+	}
+	return elems, nil
+}
+
+// fields of the arg or return value being ranged over (.name, .type, .wirename, .index)
+func (rg *RpcGen) compileDot(buf *bytes.Buffer, job Job) error {
+	if rg.dot == nil {
+		return fmt.Errorf("%s outside of {{range}}", job.ident)
+	}
+	if len(job.args) > 0 {
+		return fmt.Errorf("%s doesn't take arguments", job.ident)
+	}
+	d := rg.dot
+	switch job.ident {
+	case ".name", ".wirename":
+		if d.ref != "args" {
+			return fmt.Errorf("%s is only defined when ranging over args", job.ident)
+		}
+		if job.ident == ".name" {
+			buf.WriteString(d.name)
+		} else {
+			buf.WriteString(strconv.Quote(d.wireName))
+		}
+	case ".type":
+		buf.WriteString(d.typ)
+	case ".index":
+		buf.WriteString(strconv.Itoa(d.index))
+	default:
+		return fmt.Errorf("Unknown field %s, expected .name, .type, .wirename or .index", job.ident)
+	}
+	return nil
+}
+
+// evaluate the condition of an {{if}}. args, response and their elements
+// are true if the function has them, ctx if it takes a context,
+// and .first and .last tell where we are in a {{range}}.
+// the predicates are not, hasError, isSlice and isPointer
+func (rg *RpcGen) evalCond(f Func, job Job) (bool, error) {
+	if job.ident == "" {
+		s, _ := strconv.Unquote(job.lit)
+		return s != "", nil
+	}
+	switch job.ident {
+	case "not":
+		if len(job.args) != 1 {
+			return false, fmt.Errorf("not takes one argument, got %d", len(job.args))
+		}
+		ok, err := rg.evalCond(f, job.args[0])
+		return !ok, err
+	case "hasError":
+		// the last return value is an error
+		if len(job.args) != 0 {
+			return false, fmt.Errorf("hasError doesn't take arguments")
+		}
+		n := len(f.results)
+		return n > 0 && types.Identical(f.results[n-1], types.Universe.Lookup("error").Type()), nil
+	case "isSlice", "isPointer":
+		if len(job.args) != 1 {
+			return false, fmt.Errorf("%s takes one argument, got %d", job.ident, len(job.args))
+		}
+		typ, err := rg.typeOf(f, job.args[0])
+		if err != nil {
+			return false, fmt.Errorf("%s: %v", job.ident, err)
+		}
+		if job.ident == "isSlice" {
+			_, ok := typ.Underlying().(*types.Slice)
+			return ok, nil
+		}
+		_, ok := typ.Underlying().(*types.Pointer)
+		return ok, nil
+	}
+	if len(job.args) > 0 {
+		return false, fmt.Errorf("%s can't be used as a condition", jobString(job))
+	}
+
+	spl := strings.Split(job.ident, ".")
+	switch {
+	case job.ident == "args":
+		return len(f.ArgNames) > 0, nil
+	case job.ident == "response":
+		return len(f.ReturnTypes) > 0, nil
+	case spl[0] == "args" && len(spl) == 2 && isIndex(spl[1]):
+		i, _ := strconv.Atoi(spl[1])
+		return i >= 0 && i < len(f.ArgNames), nil
+	case spl[0] == "response" && len(spl) == 2 && isIndex(spl[1]):
+		i, _ := strconv.Atoi(spl[1])
+		return i >= 0 && i < len(f.ReturnTypes), nil
+	case job.ident == "ctx":
+		return f.CtxName != "", nil
+	case job.ident == ".first" || job.ident == ".last":
+		if rg.dot == nil {
+			return false, fmt.Errorf("%s outside of {{range}}", job.ident)
+		}
+		if job.ident == ".first" {
+			return rg.dot.index == 0, nil
+		}
+		return rg.dot.index == rg.dot.count-1, nil
+	}
+	return false, fmt.Errorf("%s can't be used as a condition", jobString(job))
+}
+
+// the go type of an arg or return value referred to by args.N, response.N or .
+func (rg *RpcGen) typeOf(f Func, job Job) (types.Type, error) {
+	spl := strings.Split(job.ident, ".")
+	switch {
+	case len(job.args) > 0:
+	case job.ident == ".":
+		if rg.dot == nil {
+			return nil, fmt.Errorf(". outside of {{range}}")
+		}
+		return rg.dot.gotype, nil
+	case spl[0] == "args" && len(spl) == 2:
+		i, err := strconv.Atoi(spl[1])
+		if err != nil || i < 0 || i >= len(f.params) {
+			return nil, fmt.Errorf("%s has no argument %s", f.Name, job.ident)
+		}
+		return f.params[i], nil
+	case spl[0] == "response" && len(spl) == 2:
+		i, err := strconv.Atoi(spl[1])
+		if err != nil || i < 0 || i >= len(f.results) {
+			return nil, fmt.Errorf("%s has no return value %s", f.Name, job.ident)
+		}
+		return f.results[i], nil
+	}
+	return nil, fmt.Errorf("expected args.N, response.N or ., got %s", jobString(job))
+}
+
+// the expression as written in the template, for error messages
+func jobString(job Job) string {
+	if job.ident == "" {
+		return job.lit
+	}
+	if len(job.args) == 0 {
+		return job.ident
+	}
+	args := make([]string, len(job.args))
+	for i, a := range job.args {
+		args[i] = jobString(a)
+	}
+	return job.ident + "(" + strings.Join(args, ", ") + ")"
+}
+
+// a template compiled once, then run for each function
+type program struct {
+	nodes  []node             // an rpc-gen template
+	gotmpl *template.Template // or a gotemplate
+}
+
+// compile the client type's template, unless it already has been
+func (rg *RpcGen) compile(clientType string) (*program, error) {
+	if prog, ok := rg.programs[clientType]; ok {
+		return prog, nil
+	}
+	tmp, ok := rg.templates[clientType]
+	if !ok {
+		return nil, fmt.Errorf("no template for %s", clientType)
+	}
+	prog := &program{}
+	if rg.gotemplates[clientType] {
+		t, err := rg.parseGoTemplate(clientType, tmp)
+		if err != nil {
+			return nil, err
+		}
+		prog.gotmpl = t
+	} else {
+		p := Parser(tmp, rg.templateLocs[clientType])
+		if err := p.run(); err != nil {
+			return nil, err
+		}
+		prog.nodes = p.results()
+	}
+	rg.programs[clientType] = prog
+	return prog, nil
+}
+
+// implement a template for a given function
+func (rg *RpcGen) makeMethod(buf *bytes.Buffer, prog *program, f Func) error {
+	if prog.gotmpl != nil {
+		if err := rg.execGoTemplate(buf, prog.gotmpl, f); err != nil {
+			return err
+		}
+	} else if err := rg.execNodes(buf, f, prog.nodes); err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "\n\n")
+	return nil
+}
+
+// an error evaluating the args of the job, unless it's
+// already been located at one of them
+func argsError(job Job, err error) error {
+	if _, ok := err.(*templateError); ok {
+		return err
+	}
+	return fmt.Errorf("%s: %v", job.ident, err)
+}
+
+// an error compiling the template for f at loc
+func locateError(loc location, f Func, err error) error {
+	if _, ok := err.(*templateError); ok {
+		return err
+	}
+	return &templateError{loc: loc, msg: fmt.Sprintf("%v (implementing %s)", err, f.Name)}
+}
+
+// write the text, compile the jobs and run the blocks of the template
+func (rg *RpcGen) execNodes(buf *bytes.Buffer, f Func, nodes []node) error {
+	for _, n := range nodes {
+		switch n.typ {
+		case nodeText:
+			fmt.Fprint(buf, n.text)
+		case nodeExpr:
+			if err := rg.compileJob(buf, f, n.job); err != nil {
+				return locateError(n.job.loc, f, err)
+			}
+		case nodeIf:
+			ok, err := rg.evalCond(f, n.job.args[0])
+			if err != nil {
+				return locateError(n.job.args[0].loc, f, err)
+			}
+			body := n.body
+			if !ok {
+				body = n.elseBody
+			}
+			if err := rg.execNodes(buf, f, body); err != nil {
+				return err
+			}
+		case nodeRange:
+			elems, err := rg.rangeElems(f, n.job.args[0])
+			if err != nil {
+				return locateError(n.job.args[0].loc, f, err)
+			}
+			if len(elems) == 0 {
+				if err := rg.execNodes(buf, f, n.elseBody); err != nil {
+					return err
+				}
+				continue
+			}
+			dot := rg.dot
+			for i := range elems {
+				rg.dot = &elems[i]
+				if err := rg.execNodes(buf, f, n.body); err != nil {
+					rg.dot = dot
+					return err
+				}
+			}
+			rg.dot = dot
+		}
+	}
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+// manage the client interface
+
+// parse the template. for each function, implement the template
+func (rg *RpcGen) implementInterface(clientType string, stringFuncs []*Func) ([]byte, error) {
+	prog, err := rg.compile(clientType)
+	if err != nil {
+		return nil, err
+	}
+	rg.clientType = clientType
+	buf := new(bytes.Buffer)
+	for _, f := range stringFuncs {
+		if err := rg.makeMethod(buf, prog, *f); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// create an interface definition containing all defined methods,
+// leaving out those with a skip directive.
+// the imports needed by the arg and return types are added to imps
+func populateInterface(fset *gotoken.FileSet, baseDef string, funcs map[string]*types.Func, dirs map[string]*funcDirectives, imps *importSet) ([]*Func, string, error) {
+	// sort functions alphabetically
+	funcNames := []string{}
+	for n, _ := range funcs {
+		if d, ok := dirs[n]; ok && d.skip {
+			continue
+		}
+		funcNames = append(funcNames, n)
+	}
+	stringFuncs := make([]*Func, len(funcNames))
+
+	sort.Strings(funcNames)
+
+	// pull off the final }
+	baseDef = baseDef[:len(baseDef)-1]
+	// extract each functions string info,
+	// add to stringFuncs and append to interface def
+	i := 0 // using append on stringFuncs was breaking ...
+	for _, name := range funcNames {
+		obj := funcs[name]
+		thisFunc, err := objectToStringFunc(fset, name, obj, imps)
+		if err != nil {
+			return nil, "", err
+		}
+		thisFunc.applyDirectives(dirs[name])
+		stringFuncs[i] = &thisFunc
+		baseDef += "\t" + name + "(" + thisFunc.argsDef() + ") ("
+		baseDef += strings.Join(thisFunc.ReturnTypes, ", ")
+		baseDef += ")\n"
+		//baseDef += fmt.Sprintf(" (*%s.Response%s, error)\n", pkg, name)
+		i += 1
+	}
+	if err := checkWireNames(fset, stringFuncs, funcs, dirs); err != nil {
+		return nil, "", err
+	}
+	return stringFuncs, baseDef + "\n}\n", nil
+}
+
+//--------------------------------------------------------------------------------
+// stringify/parse/manipulate function definitions
+
+// clean string based representation of a go function.
+// a leading context.Context arg is kept out of the ArgNames/ArgTypes
+// since it doesn't go over the wire
+type Func struct {
+	Name        string
+	ArgNames    []string
+	ArgTypes    []string // the last is ...T if Variadic
+	ReturnTypes []string
+	Variadic    bool
+
+	WireName     string   // name of the function on the wire
+	ArgWireNames []string // names of the args on the wire
+
+	CtxName string // name of the context arg, if any
+	CtxType string // qualified context.Context
+
+	Doc string // doc comment, without the rpc-gen directives
+
+	params  []types.Type // go types of the args, for the template predicates
+	results []types.Type // go types of the returns
+}
+
+// the function's args as they'd be declared, including any context
+func (f *Func) argsDef() string {
+	def := joinArgTypes(f.ArgNames, f.ArgTypes)
+	if f.CtxName == "" {
+		return def
+	}
+	if def == "" {
+		return f.CtxName + " " + f.CtxType
+	}
+	return f.CtxName + " " + f.CtxType + ", " + def
+}
+
+// the function's params renamed _ctx, _p0, _p1, .. for code generated around
+// a call to it, where their own names could shadow the core package or an import.
+// returns them as they'd be declared, and as they'd be passed on
+func (f *Func) renamedParams() (params, args []string) {
+	if f.CtxName != "" {
+		params = append(params, "_ctx "+f.CtxType)
+		args = append(args, "_ctx")
+	}
+	for i, t := range f.ArgTypes {
+		p := fmt.Sprintf("_p%d", i)
+		params = append(params, p+" "+t)
+		if strings.HasPrefix(t, "...") {
+			p += "..."
+		}
+		args = append(args, p)
+	}
+	return params, args
+}
+
+func NewFunc(name string) Func {
+	return Func{
+		Name:        name,
+		ArgNames:    []string{},
+		ArgTypes:    []string{},
+		ReturnTypes: []string{},
+	}
+}
+
+// convert a type checked function to a clean string representation of args and returns.
+// types are printed as they would be in source code, qualified by q.
+// functions with types that can't go over the wire are an error
+func objectToStringFunc(fset *gotoken.FileSet, name string, obj *types.Func, imps *importSet) (Func, error) {
+	q := imps.qualify
+	pos := fset.Position(obj.Pos())
+	sig := obj.Type().(*types.Signature)
+	if sig.TypeParams().Len() > 0 {
+		return Func{}, fmt.Errorf("%s: %s: generic functions can't be exposed over rpc", pos, name)
+	}
+	thisFunc := NewFunc(name)
+	thisFunc.Variadic = sig.Variadic()
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		n := p.Name()
+		if n == "" || n == "_" {
+			n = "arg" + strconv.Itoa(i)
+		}
+		if i == 0 && isContext(p.Type()) {
+			thisFunc.CtxName = n
+			thisFunc.CtxType = types.TypeString(p.Type(), q)
+			continue
+		}
+		if err := checkWireType(p.Type(), imps.localPath); err != nil {
+			return Func{}, fmt.Errorf("%s: %s: argument %s: %v", pos, name, n, err)
+		}
+		t := types.TypeString(p.Type(), q)
+		if thisFunc.Variadic && i == params.Len()-1 {
+			t = "..." + types.TypeString(p.Type().(*types.Slice).Elem(), q)
+		}
+		thisFunc.ArgNames = append(thisFunc.ArgNames, n)
+		thisFunc.ArgTypes = append(thisFunc.ArgTypes, t)
+		thisFunc.params = append(thisFunc.params, p.Type())
+	}
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		r := results.At(i).Type()
+		if err := checkWireType(r, imps.localPath); err != nil {
+			return Func{}, fmt.Errorf("%s: %s: return value %d: %v", pos, name, i, err)
+		}
+		thisFunc.ReturnTypes = append(thisFunc.ReturnTypes, types.TypeString(r, q))
+		thisFunc.results = append(thisFunc.results, r)
+	}
+	return thisFunc, nil
+}
+
+// check if the type is context.Context
+func isContext(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
+// check a type can be encoded to go over the wire.
+// named types are trusted to encode themselves, apart from their type arguments,
+// but they must be nameable from localPath, the package being generated
+func checkWireType(typ types.Type, localPath string) error {
+	switch t := typ.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Complex64, types.Complex128, types.UnsafePointer, types.UntypedNil:
+			return fmt.Errorf("type %s can't go over the wire", t)
+		}
+	case *types.Chan, *types.Signature:
+		return fmt.Errorf("type %s can't go over the wire", t)
+	case *types.Pointer:
+		return checkWireType(t.Elem(), localPath)
+	case *types.Slice:
+		return checkWireType(t.Elem(), localPath)
+	case *types.Array:
+		return checkWireType(t.Elem(), localPath)
+	case *types.Map:
+		if !isWireMapKey(t.Key()) {
+			return fmt.Errorf("type %s can't go over the wire: map keys must be strings, integers or encoding.TextMarshalers", t)
+		}
+		return checkWireType(t.Elem(), localPath)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if err := checkWireType(t.Field(i).Type(), localPath); err != nil {
+				return err
+			}
+		}
+	case *types.Named:
+		obj := t.Obj()
+		if pkg := obj.Pkg(); pkg != nil && pkg.Path() != localPath {
+			if !obj.Exported() {
+				return fmt.Errorf("type %s.%s isn't exported", pkg.Name(), obj.Name())
+			}
+			if !canImport(localPath, pkg.Path()) {
+				return fmt.Errorf("type %s.%s is in the internal package %s, which can't be imported here", pkg.Name(), obj.Name(), pkg.Path())
+			}
+		}
+		args := t.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			if err := checkWireType(args.At(i), localPath); err != nil {
+				return err
+			}
+		}
+	case *types.TypeParam:
+		return fmt.Errorf("type parameter %s can't go over the wire", t)
+	}
+	return nil
+}
+
+// check the package at importer may import path, which it can't if path is
+// internal to another tree
+func canImport(importer, path string) bool {
+	var parent string
+	switch {
+	case strings.HasSuffix(path, "/internal"):
+		parent = strings.TrimSuffix(path, "/internal")
+	case strings.Contains(path, "/internal/"):
+		parent = path[:strings.LastIndex(path, "/internal/")]
+	case path == "internal", strings.HasPrefix(path, "internal/"):
+		return false
+	default:
+		return true
+	}
+	return importer == parent || strings.HasPrefix(importer, parent+"/")
+}
+
+// check a map key can be encoded as a string
+func isWireMapKey(typ types.Type) bool {
+	if b, ok := typ.Underlying().(*types.Basic); ok && b.Info()&(types.IsString|types.IsInteger) != 0 {
+		return true
+	}
+	for _, t := range []types.Type{typ, types.NewPointer(typ)} {
+		if sel := types.NewMethodSet(t).Lookup(nil, "MarshalText"); sel != nil {
+			return true
+		}
+	}
+	return false
+}
+
+//--------------------------------------------------------------------------------
+// main RpcGen object
+
+type RpcGen struct {
+	templates    map[string]string
+	templateLocs map[string]location // where each template starts
+	gotemplates  map[string]bool     // client types whose template is a text/template
+	funcdefs     map[string]*funcDef // template helpers, by name
+	sets         map[string]*setDef  // serialization routines, by name
+
+	ifaceName string    // name of the base interface
+	ifaceDef  string    // source of the base interface
+	ifaceFile *ast.File // file the base interface was declared in
+
+	imports map[string]string // default imports for template functions
+	imps    *importSet        // imports needed by the generated code
+	pkgName string            // qualifier of the core package
+
+	programs map[string]*program // compiled templates, by client type
+
+	clientType string     // the client type being implemented
+	cur        Func       // the function being implemented
+	dot        *rangeElem // the element of the innermost {{range}}, if any
+}
+
+func newRpcGen() *RpcGen {
+	return &RpcGen{
+		templates:    make(map[string]string),
+		templateLocs: make(map[string]location),
+		programs:     make(map[string]*program),
+		gotemplates:  make(map[string]bool),
+		funcdefs:     make(map[string]*funcDef),
+		sets:         make(map[string]*setDef),
+		imports:      make(map[string]string),
+	}
+}
+
+// initialize the rpc generator from a pkg by parsing comments
+func initRpcGen(fset *gotoken.FileSet, pkg *ast.Package) (*RpcGen, error) {
+	rpcGen := newRpcGen()
+
+	comments, files := getComments(pkg)
+	for _, c := range comments {
+		txt := c.Text[2:]
+		if strings.HasPrefix(c.Text, "//") {
+			// gofmt puts a space after the //
+			txt = strings.TrimSpace(txt)
+		}
+		if !strings.HasPrefix(txt, "rpc-gen:") {
+			continue
+		}
+
+		txt = txt[len("rpc-gen:"):]
+		pos := fset.Position(c.Pos())
+		// the directive ends at a space or the end of the line
+		def, rest := txt, ""
+		if i := strings.IndexAny(txt, " \t\n"); i >= 0 {
+			def, rest = txt[:i], txt[i+1:]
+		}
+
+		defs := strings.Split(def, ":")
+		typ := defs[0]
+		switch typ {
+		case "template", "gotemplate":
+			// the client type, then the template up to the end of the comment
+			body := strings.TrimSuffix(txt[len(typ+":"):], "*/")
+			i := strings.IndexAny(body, " \t\n")
+			if i <= 0 {
+				return nil, fmt.Errorf("%s: rpc-gen:%s expects a client type followed by the template", pos, typ)
+			}
+			start := strings.Index(c.Text, body) + i
+			loc := location{file: pos.Filename, line: pos.Line, col: pos.Column}.advance(c.Text[:start])
+			if err := rpcGen.addTemplate(body[:i], body[i:], loc, typ == "gotemplate"); err != nil {
+				return nil, fmt.Errorf("%s: %v", pos, err)
+			}
+		case "define-set":
+			// the name, then a routine per line
+			body := strings.TrimSuffix(strings.TrimSpace(rest), "*/")
+			lines := strings.Split(body, "\n")
+			name := strings.TrimSpace(lines[0])
+			if name == "" {
+				return nil, fmt.Errorf("%s: rpc-gen:define-set expects a name", pos)
+			}
+			set, err := newSetDef(name, pos.String(), lines[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", pos, err)
+			}
+			rpcGen.sets[name] = set
+		case "define-interface":
+			// the interface is all in a comment
+			spl := strings.SplitN(rest, "\n", 2)
+			if len(spl) != 2 {
+				return nil, fmt.Errorf("%s: rpc-gen:define-interface expects a name followed by the interface declaration", pos)
+			}
+			rpcGen.ifaceName = strings.TrimSpace(spl[0])
+			defn := strings.TrimSuffix(strings.TrimSpace(spl[1]), "*/")
+			rpcGen.ifaceDef = strings.TrimSpace(defn)
+			rpcGen.ifaceFile = files[c]
+
+		case "define-func":
+			// the function definition follows the comment
+			// in the code. it may be given another name
+			fdecl := funcDeclAfter(files[c], c.End())
+			if fdecl == nil {
+				return nil, fmt.Errorf("%s: rpc-gen:define-func must be followed by a function", pos)
+			}
+			name := fdecl.Name.Name
+			if len(defs) > 1 && defs[1] != "" {
+				name = defs[1]
+			}
+			rpcGen.funcdefs[name] = newFuncDef(fdecl)
+		case "imports":
+			// an import per line, after the directive
+			rest = strings.TrimSuffix(txt[len("imports"):], "*/")
+			rpcGen.addImports(strings.Split(rest, "\n")[1:])
+		}
+	}
+	return rpcGen, nil
+}
+
+// register the template for a client type
+func (rg *RpcGen) addTemplate(clientType, tmp string, loc location, gotemplate bool) error {
+	if _, ok := rg.templates[clientType]; ok {
+		return fmt.Errorf("more than one template for %s (the other is at %s)", clientType, rg.templateLocs[clientType])
+	}
+	rg.templates[clientType] = tmp
+	rg.templateLocs[clientType] = loc
+	rg.gotemplates[clientType] = gotemplate
+	return nil
+}
+
+// resolve the signatures of the helpers, so the args passed to them can be checked.
+// like checkImplements, the package is type checked without the file being generated,
+// and type errors are ignored
+func (rg *RpcGen) resolveFuncDefs(fset *gotoken.FileSet, imp *srcImporter, pkg *ast.Package, pkgPath string) {
+	if len(rg.funcdefs) == 0 {
+		return
+	}
+	names := []string{}
+	for n := range pkg.Files {
+		if !strings.HasSuffix(n, "_test.go") {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	files := []*ast.File{}
+	for _, n := range names {
+		files = append(files, pkg.Files[n])
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{
+		Importer:         imp,
+		IgnoreFuncBodies: true,
+		Error:            func(error) {},
+	}
+	conf.Check(pkgPath, fset, files, info)
+	for _, def := range rg.funcdefs {
+		if obj, ok := info.Defs[def.decl.Name].(*types.Func); ok {
+			def.sig = obj.Type().(*types.Signature)
+		}
+	}
+}
+
+// resolve the types of each set in the scope given by imports, see setDef.resolve
+func (rg *RpcGen) resolveSets(fset *gotoken.FileSet, imp *srcImporter, imports map[string]string) error {
+	names := []string{}
+	for n := range rg.sets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := rg.sets[n].resolve(fset, imp, imports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add the imports, one per line, each a path or a name and a path
+func (rg *RpcGen) addImports(lines []string) {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+		case 1:
+			rg.imports[path.Base(fields[0])] = fields[0]
+		default:
+			rg.imports[fields[0]] = fields[1]
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rpcgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// a template with n expressions
+func benchTemplate(n int) string {
+	exprs := []string{
+		"{{lowername}}",
+		"{{args.name}}",
+		"{{args.ident}}",
+		"{{response.0}}",
+	}
+	var b strings.Builder
+	b.WriteString("func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {\n")
+	for i := 0; i < n; i++ {
+		b.WriteString("\t_ = " + exprs[i%len(exprs)] + "\n")
+	}
+	b.WriteString("\tpanic(\"unreachable\")\n}")
+	return b.String()
+}
+
+func benchFuncs(n int) []*Func {
+	funcs := make([]*Func, n)
+	for i := range funcs {
+		funcs[i] = &Func{
+			Name:         fmt.Sprintf("Func%d", i),
+			WireName:     fmt.Sprintf("func_%d", i),
+			ArgNames:     []string{"height", "name"},
+			ArgTypes:     []string{"uint", "string"},
+			ArgWireNames: []string{"height", "name"},
+			ReturnTypes:  []string{"*core.Response", "error"},
+		}
+	}
+	return funcs
+}
+
+// generation time per expression should stay flat as the template grows.
+// the template is compiled in each iteration, as it is on each run of rpc-gen
+func BenchmarkImplementInterface(b *testing.B) {
+	funcs := benchFuncs(100)
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("exprs=%d", n), func(b *testing.B) {
+			rg := newRpcGen()
+			rg.imps = newImportSet("")
+			rg.templates["*Client"] = benchTemplate(n)
+			for i := 0; i < b.N; i++ {
+				rg.programs = make(map[string]*program)
+				if _, err := rg.implementInterface("*Client", funcs); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n*len(funcs)), "ns/expr")
+		})
+	}
+}
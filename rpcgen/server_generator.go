@@ -0,0 +1,180 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// generate the server side of the rpc from the same funcs as the client
+
+// write the route table and handler registration for the server.
+// routes and argument names are taken from the same Funcs used
+// to populate the client so the two sides can't drift.
+// with typed, the handlers are the typed funcs instead
+func writeServer(buf *bytes.Buffer, funcs []*Func, pkgName string, typed bool) {
+	fmt.Fprintln(buf, "// cache all type information about each function up front")
+	fmt.Fprintln(buf, "// (func, responseStruct, argNames)")
+	fmt.Fprintln(buf, "var funcMap = map[string]*FuncWrapper{")
+	for _, f := range funcs {
+		fmt.Fprintf(buf, "\t%q: funcWrap(%s.%s, %s),\n", f.WireName, pkgName, f.Name, argNamesToSlice(f.ArgWireNames))
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	if typed {
+		writeTypedFuncs(buf, funcs, pkgName)
+		fmt.Fprintln(buf, "func initHandlers() {")
+		fmt.Fprintln(buf, "\t// HTTP endpoints")
+		fmt.Fprintln(buf, "\tfor funcName, f := range typedFuncMap {")
+		fmt.Fprintln(buf, "\t\thttp.HandleFunc(\"/\"+funcName, toTypedHttpHandler(f))")
+		fmt.Fprintln(buf, "\t}")
+		fmt.Fprintln(buf, "")
+		fmt.Fprintln(buf, "\t// JSONRPC endpoints")
+		fmt.Fprintln(buf, "\thttp.HandleFunc(\"/\", toTypedJSONRPCHandler(typedFuncMap))")
+		fmt.Fprintln(buf, "}")
+		return
+	}
+	fmt.Fprintln(buf, "func initHandlers() {")
+	fmt.Fprintln(buf, "\t// HTTP endpoints")
+	fmt.Fprintln(buf, "\tfor funcName, funcInfo := range funcMap {")
+	fmt.Fprintln(buf, "\t\thttp.HandleFunc(\"/\"+funcName, toHttpHandler(funcInfo))")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "\t// JSONRPC endpoints")
+	fmt.Fprintln(buf, "\thttp.HandleFunc(\"/\", JSONRPCHandler)")
+	fmt.Fprintln(buf, "}")
+}
+
+// the go source for a slice literal of the argument names
+func argNamesToSlice(argNames []string) string {
+	if len(argNames) == 0 {
+		return "[]string{}"
+	}
+	return "[]string{\"" + strings.Join(argNames, "\", \"") + "\"}"
+}
+
+// write the route table and handler registration for a service.
+// each route calls the method on the given instance, so several
+// instances can be served side by side on different muxes.
+// with typed, the handlers are the typed funcs instead
+func writeServiceServer(buf *bytes.Buffer, funcs []*Func, service string, typed bool) {
+	fmt.Fprintln(buf, "// cache all type information about each of the service's methods")
+	fmt.Fprintln(buf, "// (method, responseStruct, argNames)")
+	fmt.Fprintf(buf, "func newFuncMap(svc %s) map[string]*FuncWrapper {\n", service)
+	fmt.Fprintln(buf, "\treturn map[string]*FuncWrapper{")
+	for _, f := range funcs {
+		fmt.Fprintf(buf, "\t\t%q: funcWrap(svc.%s, %s),\n", f.WireName, f.Name, argNamesToSlice(f.ArgWireNames))
+	}
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	if typed {
+		writeTypedServiceFuncs(buf, funcs, service)
+		fmt.Fprintf(buf, "func initHandlers(mux *http.ServeMux, svc %s) {\n", service)
+		fmt.Fprintln(buf, "\tfuncMap := newTypedFuncMap(svc)")
+		fmt.Fprintln(buf, "")
+		fmt.Fprintln(buf, "\t// HTTP endpoints")
+		fmt.Fprintln(buf, "\tfor funcName, f := range funcMap {")
+		fmt.Fprintln(buf, "\t\tmux.HandleFunc(\"/\"+funcName, toTypedHttpHandler(f))")
+		fmt.Fprintln(buf, "\t}")
+		fmt.Fprintln(buf, "")
+		fmt.Fprintln(buf, "\t// JSONRPC endpoints")
+		fmt.Fprintln(buf, "\tmux.HandleFunc(\"/\", toTypedJSONRPCHandler(funcMap))")
+		fmt.Fprintln(buf, "}")
+		return
+	}
+	fmt.Fprintf(buf, "func initHandlers(mux *http.ServeMux, svc %s) {\n", service)
+	fmt.Fprintln(buf, "\tfuncMap := newFuncMap(svc)")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "\t// HTTP endpoints")
+	fmt.Fprintln(buf, "\tfor funcName, funcInfo := range funcMap {")
+	fmt.Fprintln(buf, "\t\tmux.HandleFunc(\"/\"+funcName, toHttpHandler(funcInfo))")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "\t// JSONRPC endpoints")
+	fmt.Fprintln(buf, "\tmux.HandleFunc(\"/\", toJSONRPCHandler(funcMap))")
+	fmt.Fprintln(buf, "}")
+}
+
+//--------------------------------------------------------------------------------
+// typed handlers, which decode each arg into its concrete type and
+// call the function directly, instead of through reflection
+
+// the typed funcs return a result and an error, so the
+// functions may return at most a result and an error
+func checkTyped(funcs []*Func) error {
+	for _, f := range funcs {
+		n := len(f.ReturnTypes)
+		if n > 2 || (n == 2 && f.ReturnTypes[1] != "error") {
+			return fmt.Errorf("%s: typed handlers need the function to return at most a result and an error", f.Name)
+		}
+	}
+	return nil
+}
+
+// write the table of typed funcs, and each func
+func writeTypedFuncs(buf *bytes.Buffer, funcs []*Func, pkgName string) {
+	fmt.Fprintln(buf, "// call each function with its args decoded by a decodeParam")
+	fmt.Fprintln(buf, "var typedFuncMap = map[string]typedFunc{")
+	for _, f := range funcs {
+		fmt.Fprintf(buf, "\t%q: call%s,\n", f.WireName, f.Name)
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+	for _, f := range funcs {
+		fmt.Fprintf(buf, "func call%s(_ctx context.Context, _param decodeParam) (interface{}, error) {\n", f.Name)
+		writeTypedCall(buf, "\t", f, pkgName+"."+f.Name)
+		fmt.Fprintln(buf, "}")
+		fmt.Fprintln(buf, "")
+	}
+}
+
+// write the table of typed funcs for a service, calling the methods on svc
+func writeTypedServiceFuncs(buf *bytes.Buffer, funcs []*Func, service string) {
+	fmt.Fprintln(buf, "// call each of the service's methods with its args decoded by a decodeParam")
+	fmt.Fprintf(buf, "func newTypedFuncMap(svc %s) map[string]typedFunc {\n", service)
+	fmt.Fprintln(buf, "\treturn map[string]typedFunc{")
+	for _, f := range funcs {
+		fmt.Fprintf(buf, "\t\t%q: func(_ctx context.Context, _param decodeParam) (interface{}, error) {\n", f.WireName)
+		writeTypedCall(buf, "\t\t\t", f, "svc."+f.Name)
+		fmt.Fprintln(buf, "\t\t},")
+	}
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "")
+}
+
+// write the body of a typed func: decode each arg with _param,
+// then call the function with them and return what it does.
+// the args are decoded into _p0, _p1, .. (as renamedParams names them,
+// like the local and mock clients) rather than the names of the params,
+// which could shadow the core package or an import
+func writeTypedCall(buf *bytes.Buffer, indent string, f *Func, callee string) {
+	params, args := f.renamedParams()
+	if f.CtxName != "" {
+		params = params[1:]
+	}
+	for i, p := range params {
+		name, t, _ := strings.Cut(p, " ")
+		if strings.HasPrefix(t, "...") {
+			t = "[]" + strings.TrimPrefix(t, "...")
+		}
+		fmt.Fprintf(buf, "%svar %s %s\n", indent, name, t)
+		fmt.Fprintf(buf, "%sif err := _param(%d, %q, &%s); err != nil {\n", indent, i, f.ArgWireNames[i], name)
+		fmt.Fprintf(buf, "%s\treturn nil, err\n", indent)
+		fmt.Fprintf(buf, "%s}\n", indent)
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+	switch {
+	case len(f.ReturnTypes) == 0:
+		fmt.Fprintf(buf, "%s%s\n", indent, call)
+		fmt.Fprintf(buf, "%sreturn nil, nil\n", indent)
+	case len(f.ReturnTypes) == 2:
+		fmt.Fprintf(buf, "%sreturn %s\n", indent, call)
+	case f.ReturnTypes[0] == "error":
+		fmt.Fprintf(buf, "%sreturn nil, %s\n", indent, call)
+	default:
+		fmt.Fprintf(buf, "%sreturn %s, nil\n", indent, call)
+	}
+}
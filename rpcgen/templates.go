@@ -0,0 +1,121 @@
+package rpcgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------
+// templates in standalone files (-templates)
+
+// load the templates, imports and sets from template files, or directories of .tmpl files.
+// a file is a list of sections, each starting with a directive on a line of its own:
+//
+//	rpc-gen:imports
+//	net/http
+//	rpc github.com/tendermint/tendermint/rpc
+//
+//	rpc-gen:template:*ClientHTTP
+//	func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+//		...
+//	}
+//
+// templates (and gotemplates) are keyed by client type. define-set
+// may be used too, but helpers must be declared in go
+func (rg *RpcGen) loadTemplateFiles(paths []string) error {
+	for _, p := range paths {
+		files, err := templateFiles(p)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if err := rg.loadTemplateFile(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// the file itself, or the .tmpl files in the directory
+func templateFiles(p string) ([]string, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{p}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(p, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tmpl files in %s", p)
+	}
+	return files, nil
+}
+
+// a directive in a template file, and the lines up to the next one
+type templateSection struct {
+	directive string
+	line      int
+	body      string
+}
+
+func (rg *RpcGen) loadTemplateFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	sections := []*templateSection{}
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.HasPrefix(line, "rpc-gen:") {
+			sections = append(sections, &templateSection{
+				directive: strings.TrimSpace(line[len("rpc-gen:"):]),
+				line:      i + 1,
+			})
+		} else if len(sections) > 0 {
+			sections[len(sections)-1].body += line
+		} else if strings.TrimSpace(line) != "" {
+			return fmt.Errorf("%s:%d: expected an rpc-gen directive", filename, i+1)
+		}
+	}
+
+	for _, s := range sections {
+		fields := strings.Fields(s.directive)
+		if len(fields) == 0 {
+			return fmt.Errorf("%s:%d: empty rpc-gen directive", filename, s.line)
+		}
+		defs := strings.SplitN(fields[0], ":", 2)
+		switch typ := defs[0]; typ {
+		case "template", "gotemplate":
+			if len(defs) != 2 || defs[1] == "" || len(fields) > 1 {
+				return fmt.Errorf("%s:%d: expected rpc-gen:%s:<client type>", filename, s.line, typ)
+			}
+			// the template starts on the next line, after any blank lines
+			tmp := strings.TrimSpace(s.body)
+			loc := location{file: filename, line: s.line + 1, col: 1}.advance(s.body[:strings.Index(s.body, tmp)])
+			if err := rg.addTemplate(defs[1], tmp, loc, typ == "gotemplate"); err != nil {
+				return fmt.Errorf("%s:%d: %v", filename, s.line, err)
+			}
+		case "imports":
+			rg.addImports(strings.Split(s.body, "\n"))
+		case "define-set":
+			if len(fields) != 2 {
+				return fmt.Errorf("%s:%d: expected rpc-gen:define-set <name>", filename, s.line)
+			}
+			set, err := newSetDef(fields[1], fmt.Sprintf("%s:%d", filename, s.line), strings.Split(s.body, "\n"))
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", filename, s.line, err)
+			}
+			rg.sets[fields[1]] = set
+		default:
+			return fmt.Errorf("%s:%d: unknown directive rpc-gen:%s in a template file", filename, s.line, fields[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package core
+
+func Get(key string, version string) ([]byte, error) {
+	return nil, nil
+}
+
+func Put(key string, value []byte) (int, error) {
+	return 0, nil
+}
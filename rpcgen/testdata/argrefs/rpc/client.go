@@ -0,0 +1,24 @@
+package rpc
+
+type ClientHTTP struct{}
+
+func (c ClientHTTP) send(key string, v interface{}, b []byte) error {
+	return nil
+}
+
+// references to single args and return values
+
+/*rpc-gen:template:ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	k := {{args.0}}
+	var second struct { {{args.1.def}} }
+	second.{{args.1.ident}} = {{args.1.ident}}
+	var result {{response.0}}
+	return result, c.send(k, second, {{convert(args.1)}})
+}
+*/
+
+// rpc-gen:define-func
+func convert(v []byte) []byte {
+	return v
+}
@@ -0,0 +1,24 @@
+package core
+
+import "context"
+
+type Account struct {
+	Address []byte
+	Balance uint64
+}
+
+func Ping() error {
+	return nil
+}
+
+func NumPeers() int {
+	return 0
+}
+
+func GetAccount(ctx context.Context, address []byte) (*Account, error) {
+	return nil, nil
+}
+
+func ListAccounts(addresses [][]byte, limit int) ([]Account, error) {
+	return nil, nil
+}
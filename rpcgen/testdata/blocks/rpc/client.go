@@ -0,0 +1,28 @@
+package rpc
+
+import "context"
+
+type ClientHTTP struct{}
+
+func (c *ClientHTTP) call(ctx context.Context, method string, params map[string]interface{}, result interface{}) error {
+	return nil
+}
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	{{if args}}params := map[string]interface{}{ {{range args}}
+		{{.wirename}}: {{encode(.)}}, // arg {{.index}}: {{.type}}{{if isSlice .}} (a slice){{end}}{{end}}
+	}{{else}}var params map[string]interface{}{{end}}
+	{{if ctx}}// called with the caller's context{{else}}// called with a background context{{end}}
+	// returns{{range response}} {{.type}}{{if not .last}},{{end}}{{else}} nothing{{end}}
+	var result {{response.0}}{{if isPointer response.0}} // a pointer{{end}}
+	{{if hasError}}err := c.call({{ctx}}, {{lowername}}, params, &result)
+	{{if response.1}}return result, err{{else}}return err{{end}}{{else}}c.call({{ctx}}, {{lowername}}, params, &result)
+	return result{{end}}
+}
+*/
+
+// rpc-gen:define-func
+func encode(v interface{}) interface{} {
+	return v
+}
@@ -0,0 +1,9 @@
+package core
+
+type Tx interface {
+	Hash() []byte
+}
+
+func Broadcast(tx Tx) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package rpc
+
+type Client struct{}
+
+func (c *Client) send(params ...string) error {
+	return nil
+}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	return c.send({{lowername}}, {{join(lowername, hex(args.0))}})
+}
+*/
+
+// rpc-gen:define-func
+func hex(b []byte) string {
+	return ""
+}
+
+// rpc-gen:define-func
+func join(args ...interface{}) string {
+	return ""
+}
@@ -0,0 +1,6 @@
+package core
+
+// rpc-gen:param version=v
+func Get(key string) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,10 @@
+package core
+
+func GetBlock(height uint) (string, error) {
+	return "", nil
+}
+
+// rpc-gen:name get_block
+func LoadBlock(height uint) (string, error) {
+	return "", nil
+}
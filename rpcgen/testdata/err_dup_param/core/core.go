@@ -0,0 +1,6 @@
+package core
+
+// rpc-gen:param from=height
+func Blocks(from, height uint) ([]string, error) {
+	return nil, nil
+}
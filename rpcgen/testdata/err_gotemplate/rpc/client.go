@@ -0,0 +1,9 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:gotemplate:*Client
+func (c {{.Client}}) {{.Name}}({{.Signature}}) ({{.Results}}) {
+	panic({{quote .Nme}})
+}
+*/
@@ -0,0 +1,9 @@
+package core
+
+type Status struct {
+	Height uint64
+}
+
+func GetStatus() (*Status, error) {
+	return &Status{}, nil
+}
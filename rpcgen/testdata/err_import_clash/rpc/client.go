@@ -0,0 +1,14 @@
+package rpc
+
+type ClientHTTP struct{}
+
+/*rpc-gen:imports
+str strings
+strs strings
+*/
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	return nil, nil
+}
+*/
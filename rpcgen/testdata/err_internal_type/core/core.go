@@ -0,0 +1,7 @@
+package core
+
+import "github.com/ebuchman/go-rpc-gen/rpcgen/testdata/err_internal_type/core/internal/types"
+
+func Keys() ([]types.Key, error) {
+	return nil, nil
+}
@@ -0,0 +1,10 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	var result {{response.0}}
+	return {{decode(response.0, &result)}}
+}
+*/
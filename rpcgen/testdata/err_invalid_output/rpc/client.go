@@ -0,0 +1,9 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	panic({{lowername}}
+}
+*/
@@ -0,0 +1,9 @@
+package core
+
+func Status() (string, error) {
+	return "", nil
+}
+
+func StatusFunc() error {
+	return nil
+}
@@ -0,0 +1,5 @@
+package core
+
+func Get(key string, version int) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,9 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	panic({{lowername}})
+}
+*/
@@ -0,0 +1,15 @@
+package rpc
+
+/*rpc-gen:define-interface Client
+type Client interface {
+	Address() string
+}
+*/
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	panic({{lowername}})
+}
+*/
@@ -0,0 +1,5 @@
+package core
+
+func Send(data []byte) error {
+	return nil
+}
@@ -0,0 +1,14 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	return c.call({{wire args}})
+}
+*/
+
+/*rpc-gen:define-set wire
+[]byte   bytesToString
+types.Tx txToString
+*/
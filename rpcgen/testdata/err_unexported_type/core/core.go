@@ -0,0 +1,9 @@
+package core
+
+type secret struct {
+	Key string
+}
+
+func Get(s secret) error {
+	return nil
+}
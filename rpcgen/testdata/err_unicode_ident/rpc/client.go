@@ -0,0 +1,10 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	// Größe → {{lowrname}}
+	return 0, nil
+}
+*/
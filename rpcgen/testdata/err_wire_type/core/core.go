@@ -0,0 +1,5 @@
+package core
+
+func Subscribe(events chan string) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+package core
+
+import "context"
+
+type Peer struct {
+	Addr string
+}
+
+// Peers connected to the node.
+// rpc-gen:param max=max_peers
+func Peers(ctx context.Context, max int, tags ...string) ([]*Peer, error) {
+	return nil, nil
+}
+
+func Dial(addr string, persistent bool) error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"net/url"
+)
+
+type ClientHTTP struct {
+	addr string
+}
+
+func (c *ClientHTTP) post(ctx context.Context, method string, values url.Values, result interface{}) error {
+	return nil
+}
+
+/*rpc-gen:imports
+net/url
+strconv
+*/
+
+/*rpc-gen:gotemplate:*ClientHTTP {{with .Doc}}// {{.}}
+{{end}}func (c {{.Client}}) {{.Name}}({{.Signature}}) ({{.Results}}) {
+	values := url.Values{}{{range .Args}}
+	values.Set({{quote .WireName}}, {{encode "wire" .}}) // {{.Type}}{{if .IsSlice}}, a slice{{end}}{{end}}
+	{{- if eq (len .Returns) 2}}
+	var result {{(index .Returns 0).Type}}
+	err := c.post({{.Ctx}}, {{quote .WireName}}, values, &result)
+	return result, err
+	{{- else}}
+	return c.post({{.Ctx}}, {{quote .WireName}}, values, nil)
+	{{- end}}
+}
+
+// {{.Name}}Checked is {{.Name}} in {{.Pkg}}{{if .Variadic}}, variadic{{end}}{{if .HasError}}, returning an error{{end}}
+func (c {{.Client}}) {{.Name}}Checked({{.Signature}}) error {
+	return {{helper "validate" .Args}}
+}
+*/
+
+/*rpc-gen:define-set wire
+int  strconv.Itoa
+bool strconv.FormatBool
+_    toString
+*/
+
+func toString(v interface{}) string {
+	return ""
+}
+
+// rpc-gen:define-func
+func validate(args ...interface{}) error {
+	return nil
+}
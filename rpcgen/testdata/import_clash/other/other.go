@@ -0,0 +1,5 @@
+package other
+
+func Call(method string, result interface{}) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+package rpc
+
+type ClientHTTP struct{}
+
+// the templates' core is another package, so the
+// core package is imported under another name
+
+/*rpc-gen:imports
+core github.com/ebuchman/go-rpc-gen/rpcgen/testdata/import_clash/other
+*/
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	var result {{response.0}}
+	err := core.Call({{lowername}}, &result)
+	return result, err
+}
+*/
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/ebuchman/go-rpc-gen/rpcgen/testdata/imports/core/types"
+	othertypes "github.com/ebuchman/go-rpc-gen/rpcgen/testdata/imports/other/types"
+)
+
+type Result struct {
+	Tx types.Tx
+}
+
+func Fetch(u *url.URL, timeout time.Duration) (*types.Tx, error) {
+	return nil, nil
+}
+
+func Convert(tx othertypes.Tx) (types.Tx, error) {
+	return types.Tx{}, nil
+}
+
+func Index(txs map[types.ID][]othertypes.Tx) (map[types.ID]Result, error) {
+	return nil, nil
+}
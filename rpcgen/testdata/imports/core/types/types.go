@@ -0,0 +1,8 @@
+package types
+
+type ID string
+
+type Tx struct {
+	ID   ID
+	Data []byte
+}
@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"strings"
+)
+
+type ClientHTTP struct {
+	addr string
+}
+
+func (c *ClientHTTP) get(path string, result interface{}) error {
+	return nil
+}
+
+// the names of these imports are kept, even for the
+// packages in the core functions' signatures (neturl.URL).
+// those that aren't used are left out of the generated code
+
+/*rpc-gen:imports
+neturl net/url
+strings
+os
+github.com/ebuchman/go-rpc-gen/rpcgen/testdata/imports/other/types
+*/
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	var result {{response.0}}
+	err := c.get(strings.ToLower({{lowername}}), &result)
+	return result, err
+}
+*/
+
+func lower(s string) string {
+	return strings.ToLower(s)
+}
@@ -0,0 +1,23 @@
+package core
+
+// Balance of the account.
+// rpc-gen:name get_balance
+// rpc-gen:param addr=address
+func Balance(addr string) (uint64, error) {
+	return 0, nil
+}
+
+// rpc-gen:unsafe
+// rpc-gen:param key=private_key
+func SetKey(key []byte, overwrite bool) error {
+	return nil
+}
+
+// rpc-gen:skip
+func Shutdown() error {
+	return nil
+}
+
+func NetInfo() (map[string]int, error) {
+	return nil, nil
+}
@@ -0,0 +1,4 @@
+package core
+
+// excluded from the rpc
+func Internal() {}
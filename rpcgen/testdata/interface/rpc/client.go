@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"io"
+	"net/http"
+)
+
+/*rpc-gen:define-interface Client
+type Client interface {
+	io.Closer
+
+	// the remote address
+	Address() string
+}
+*/
+
+type ClientA struct {
+	addr string
+}
+
+func (c *ClientA) Address() string { return c.addr }
+func (c *ClientA) Close() error    { return nil }
+
+var _ io.Closer = (*ClientA)(nil)
+
+type ClientB struct {
+	http.Client
+	addr string
+}
+
+func (c *ClientB) Address() string { return c.addr }
+func (c *ClientB) Close() error    { return nil }
+
+/*rpc-gen:template:*ClientA
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	// {{lowername}} with {{args.name}}
+	panic("A")
+}
+*/
+
+/*rpc-gen:template:*ClientB
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	// {{lowername}} with {{args.name}}
+	panic("B")
+}
+*/
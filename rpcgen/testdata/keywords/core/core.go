@@ -0,0 +1,30 @@
+package core
+
+import "context"
+
+type Block struct {
+	Height uint
+	Hash   []byte
+}
+
+// GetBlock returns the block at the height
+func GetBlock(height uint) (*Block, error) {
+	return nil, nil
+}
+
+func Blocks(ctx context.Context, minHeight, maxHeight uint) ([]*Block, error) {
+	return nil, nil
+}
+
+func Status() (string, error) {
+	return "", nil
+}
+
+// names on the wire are quoted as go strings
+// rpc-gen:name search"\\
+// rpc-gen:param query=q"\\
+func Search(query string, tags ...string) ([]*Block, error) {
+	return nil, nil
+}
+
+func unexported() {}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+type ClientHTTP struct {
+	addr string
+}
+
+func (c *ClientHTTP) call(ctx context.Context, method string, names []string, params []string, result interface{}) error {
+	return nil
+}
+
+/*rpc-gen:template:*ClientHTTP
+// {{name}} calls {{lowername}} on the {{client}}
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	// args: {{args.ident}}
+	var names []string = {{args.name}}
+	params := []string{ {{wire(args, "hex")}} }
+	_ = {{hash(args, lowername, "seed")}}
+	var result {{response.0}}
+	if err := c.call({{ctx}}, {{lowername}}, names, params, &result); err != nil {
+		return result, err
+	}
+	return {{wire.decode(response.0, result)}}.({{response.0}}), nil
+}
+*/
+
+/*rpc-gen:define-set wire
+uint     uintToString
+[]string stringsToString
+_        jsonToString     fromJSON
+*/
+
+func uintToString(i uint, opts ...string) string {
+	return strconv.FormatUint(uint64(i), 10)
+}
+
+func stringsToString(s []string, opts ...string) string {
+	return ""
+}
+
+func jsonToString(v interface{}, opts ...string) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func fromJSON(v interface{}) interface{} {
+	return v
+}
+
+// rpc-gen:define-func
+func hash(args ...interface{}) string {
+	return ""
+}
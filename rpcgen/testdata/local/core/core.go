@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+type Status struct {
+	Height uint64
+	Peers  []string
+}
+
+func GetStatus(ctx context.Context) (*Status, error) {
+	return &Status{Height: 1}, nil
+}
+
+func Sum(xs ...int) (int, error) {
+	total := 0
+	for _, x := range xs {
+		total += x
+	}
+	return total, nil
+}
+
+func Fail(code int) (string, error) {
+	return "", fmt.Errorf("failed with %d", code)
+}
+
+func Version() string {
+	return "1.0"
+}
+
+func Reset(height uint64) {}
+
+// params named after the core package and an import
+func Find(ctx context.Context, core string, context uint64) (*Status, error) {
+	return nil, nil
+}
@@ -0,0 +1,10 @@
+package rpc
+
+/*rpc-gen:define-interface Client
+type Client interface {
+	// the remote address
+	Address() string
+}
+*/
+
+func (c *ClientLocal) Address() string { return "" }
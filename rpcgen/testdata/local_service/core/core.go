@@ -0,0 +1,26 @@
+package core
+
+type Node struct {
+	height uint
+}
+
+// Height of the chain
+func (n *Node) Height() (uint, error) {
+	return n.height, nil
+}
+
+func (n *Node) SetHeight(height uint) error {
+	n.height = height
+	return nil
+}
+
+func (n Node) Version() (string, error) {
+	return "1.0", nil
+}
+
+func (n *Node) private() {}
+
+// functions aren't part of the service
+func NewNode() *Node {
+	return &Node{}
+}
@@ -0,0 +1,24 @@
+package core
+
+import "context"
+
+type Status struct {
+	Height uint64
+}
+
+func GetStatus(ctx context.Context) (*Status, error) {
+	return &Status{}, nil
+}
+
+func Balance(addr string) (uint64, error) {
+	return 0, nil
+}
+
+func Broadcast(ctx context.Context, txs ...[]byte) error {
+	return nil
+}
+
+// params named after the core package and an import of the mock
+func Lookup(core string, sync bool) (*Status, error) {
+	return nil, nil
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import "io"
+
+/*rpc-gen:define-interface Client
+type Client interface {
+	io.Closer
+
+	// the remote address
+	Address() string
+	SetHeaders(kv ...string)
+}
+*/
+
+type ClientHTTP struct {
+	addr string
+}
+
+func (c *ClientHTTP) Address() string         { return c.addr }
+func (c *ClientHTTP) Close() error            { return nil }
+func (c *ClientHTTP) SetHeaders(kv ...string) {}
+
+var _ io.Closer = (*ClientHTTP)(nil)
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	panic({{lowername}})
+}
+*/
@@ -0,0 +1,14 @@
+package core
+
+import "example.com/app/types"
+
+// the packages of the module are imported by their paths in the module,
+// not by their paths on the $GOPATH, wherever the generator is run from
+
+type Account struct {
+	Balance uint64
+}
+
+func GetAccount(address types.Address) (*Account, error) {
+	return &Account{}, nil
+}
@@ -0,0 +1,27 @@
+package rpc
+
+/*rpc-gen:define-interface Client
+type Client interface {
+	Address() string
+}
+*/
+
+type ClientHTTP struct {
+	addr string
+}
+
+func (c *ClientHTTP) Address() string { return c.addr }
+
+func (c *ClientLocal) Address() string { return "local" }
+
+func (c *ClientHTTP) get(method string, result interface{}) error {
+	return nil
+}
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	var result {{response.0}}
+	err := c.get({{lowername}}, &result)
+	return result, err
+}
+*/
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"time"
+
+	"github.com/ebuchman/go-rpc-gen/rpcgen/testdata/outpkg_types/rpc"
+)
+
+// types of the package being generated for aren't qualified, and
+// since no core types are used, the core package isn't imported
+
+func Configure(opts rpc.Options) error {
+	return nil
+}
+
+func Uptime() (time.Duration, error) {
+	return 0, nil
+}
@@ -0,0 +1,19 @@
+package rpc
+
+type Options struct {
+	Verbose bool
+}
+
+type ClientHTTP struct{}
+
+func (c *ClientHTTP) get(method string, result interface{}) error {
+	return nil
+}
+
+/*rpc-gen:template:*ClientHTTP
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	{{if response.1}}var result {{response.0}}
+	err := c.get({{lowername}}, &result)
+	return result, err{{else}}return c.get({{lowername}}, nil){{end}}
+}
+*/
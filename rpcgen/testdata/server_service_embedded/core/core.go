@@ -0,0 +1,24 @@
+package core
+
+import "sync"
+
+type Store struct{}
+
+func (s *Store) Get(key string) ([]byte, error) {
+	return nil, nil
+}
+
+// only the methods declared on Node are served,
+// not Lock and Unlock, nor the Store's Get
+type Node struct {
+	sync.Mutex
+	*Store
+
+	height uint
+}
+
+func (n *Node) Height() (uint, error) {
+	n.Lock()
+	defer n.Unlock()
+	return n.height, nil
+}
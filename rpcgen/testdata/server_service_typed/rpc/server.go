@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// the handlers the generated code serves the functions with. they're
+// written with the server, as in the example, and only stubbed out here
+
+type FuncWrapper struct {
+	f        interface{}
+	argNames []string
+}
+
+func funcWrap(f interface{}, args []string) *FuncWrapper {
+	return &FuncWrapper{f: f, argNames: args}
+}
+
+func toHttpHandler(funcInfo *FuncWrapper) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
+func JSONRPCHandler(w http.ResponseWriter, r *http.Request) {}
+
+func toJSONRPCHandler(funcMap map[string]*FuncWrapper) func(http.ResponseWriter, *http.Request) {
+	return JSONRPCHandler
+}
+
+type decodeParam func(i int, name string, v interface{}) error
+
+type typedFunc func(ctx context.Context, param decodeParam) (interface{}, error)
+
+func toTypedHttpHandler(f typedFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
+func toTypedJSONRPCHandler(funcMap map[string]typedFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
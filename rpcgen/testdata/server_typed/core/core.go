@@ -0,0 +1,34 @@
+package core
+
+import (
+	"context"
+	"net/url"
+)
+
+type Status struct {
+	Height uint64
+}
+
+func GetStatus(ctx context.Context) (*Status, error) {
+	return &Status{}, nil
+}
+
+// rpc-gen:param addr=address
+func Balance(addr string) (uint64, error) {
+	return 0, nil
+}
+
+func Broadcast(ctx context.Context, txs ...[]byte) error {
+	return nil
+}
+
+func Peers(filter url.Values) []string {
+	return nil
+}
+
+func Reset() {}
+
+// params named after the core package and an import
+func Lookup(core string, url *url.URL) (string, error) {
+	return "", nil
+}
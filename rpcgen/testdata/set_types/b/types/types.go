@@ -0,0 +1,3 @@
+package types
+
+type Key string
@@ -0,0 +1,14 @@
+package core
+
+import (
+	"github.com/ebuchman/go-rpc-gen/rpcgen/testdata/set_types/a/types"
+	btypes "github.com/ebuchman/go-rpc-gen/rpcgen/testdata/set_types/b/types"
+)
+
+func Send(tx types.Tx, data []uint8) error {
+	return nil
+}
+
+func Get(key btypes.Key, keys []btypes.Key) error {
+	return nil
+}
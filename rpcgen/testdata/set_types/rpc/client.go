@@ -0,0 +1,44 @@
+package rpc
+
+import "fmt"
+
+type Client struct{}
+
+func (c *Client) call(params ...string) error {
+	return nil
+}
+
+/*rpc-gen:template:*Client
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	return c.call({{wire args}})
+}
+*/
+
+// the set's types are matched to the args however they're printed:
+// []uint8 is []byte, and types.Tx is printed types2.Tx
+
+/*rpc-gen:define-set wire
+[]byte      bytesToString
+types.Tx    txToString
+btypes.Key  keyToString
+[]btypes.Key keysToString
+_           fmt.Sprint
+*/
+
+func bytesToString(b []byte) string {
+	return string(b)
+}
+
+func txToString(tx interface{}) string {
+	return ""
+}
+
+func keyToString(key interface{}) string {
+	return ""
+}
+
+func keysToString(keys interface{}) string {
+	return ""
+}
+
+var _ = fmt.Sprint
@@ -0,0 +1,9 @@
+package core
+
+func Echo(msg string) (string, error) {
+	return msg, nil
+}
+
+func Sum(xs []int) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,28 @@
+package rpc
+
+// the templates are in templates/
+
+type ClientJSON struct{}
+
+type ClientText struct{}
+
+func (c *ClientJSON) call(method string, params []byte, result interface{}) error {
+	return nil
+}
+
+func (c *ClientText) call(method string, params []string) (string, error) {
+	return "", nil
+}
+
+/*rpc-gen:define-set text
+[]int intsToString
+_     fmt.Sprint   parseText
+*/
+
+func intsToString(xs []int) string {
+	return ""
+}
+
+func parseText(s string, v interface{}) error {
+	return nil
+}
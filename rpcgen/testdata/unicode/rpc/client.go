@@ -0,0 +1,16 @@
+package rpc
+
+type Client struct{}
+
+/*rpc-gen:template:*Client
+// {{name}} → {{lowername}}, en µs
+func (c {{client}}) {{name}}({{args.def}}) ({{response}}) {
+	// Größe der Antwort: {{args.name}} → {{unit(lowername, "µs → ms")}}
+	return 0, nil
+}
+*/
+
+// rpc-gen:define-func
+func unit(args ...interface{}) string {
+	return ""
+}
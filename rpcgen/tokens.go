@@ -1,4 +1,4 @@
-package main
+package rpcgen
 
 import (
 	"fmt"
@@ -18,6 +18,14 @@ func (t token) String() string {
 	return s + fmt.Sprintf("%q", t.val)
 }
 
+// the token as it appears in an error message
+func (t token) desc() string {
+	if t.typ == tokenEOFTy {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
 // token types
 type tokenType int
 
@@ -36,6 +44,14 @@ func (t tokenType) String() string {
 		return "[String]"
 	case tokenSpaceTy:
 		return "[Space]"
+	case tokenLeftBraceTy:
+		return "[LeftBrace]"
+	case tokenRightBraceTy:
+		return "[RightBrace]"
+	case tokenCommaTy:
+		return "[Comma]"
+	case tokenQuoteTy:
+		return "[Quote]"
 	}
 	return "[Unknown]"
 }
@@ -48,10 +64,12 @@ const (
 	tokenRightBracesTy                     // }}
 	tokenLeftCurlBraceTy                   // {
 	tokenRightCurlBraceTy                  //}
-	tokenStringTy                          // variable name, contents of quotes, comments
+	tokenStringTy                          // identifier or reference inside {{ }}, go code outside
 	tokenLeftBraceTy                       // (
 	tokenRightBraceTy                      // )
 	tokenSpaceTy
+	tokenCommaTy // ,
+	tokenQuoteTy // string literal, with its quotes
 )
 
 // tokens
@@ -60,8 +78,12 @@ var (
 	tokenRightBraces    = "}}"
 	tokenLeftCurlBrace  = "{"
 	tokenRightCurlBrace = "}"
-	tokenLeftBrace      = "("
-	tokenRightBrace     = ")"
 	tokenSpace          = " "
-	tokenChars          = "abcdefghijklmnopqrstuvwqyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890-/_.*,\n\t:+-/=`'\"!%&|[]>< {()"
+)
+
+// single rune tokens inside {{ }}
+const (
+	tokenLeftBrace  = '('
+	tokenRightBrace = ')'
+	tokenComma      = ','
 )
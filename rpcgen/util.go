@@ -0,0 +1,534 @@
+package rpcgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//--------------------------------------------------------------------------------
+// string manipulation of source code
+
+// join argument names and types (for function def)
+func joinArgTypes(names, types []string) string {
+	union := make([]string, len(names))
+	for i, n := range names {
+		union[i] = fmt.Sprintf("%s %s", n, types[i])
+	}
+	return strings.Join(union, ", ")
+}
+
+// convert camel case to lowercase (CamelCase => camel_case)
+func CamelToLower(s string) string {
+	lower := ""
+	for i := 0; i < len(s); i++ {
+		if unicode.IsUpper(rune(s[i])) && i != 0 {
+			lower += "_"
+		}
+		lower += string(unicode.ToLower(rune(s[i])))
+	}
+	return lower
+}
+
+//--------------------------------------------------------------------------------
+// get lists of nodes (comments, funcs, imports) from pkg
+
+// return a list of all comments, and the file each is in
+func getComments(pkg *ast.Package) ([]*ast.Comment, map[*ast.Comment]*ast.File) {
+	// is this a comment or what
+	comments := []*ast.Comment{}
+	files := make(map[*ast.Comment]*ast.File)
+	fs := pkg.Files
+	for _, f := range fs {
+		for _, c := range f.Comments {
+			for _, cc := range c.List {
+				comments = append(comments, cc)
+				files[cc] = f
+			}
+		}
+	}
+	return comments, files
+}
+
+// returns a list of all exported functions in a pkg,
+// excluding those declared in the excluded files
+func getFuncs(fset *gotoken.FileSet, pkg *types.Package, excludes []string) map[string]*types.Func {
+	objs := make(map[string]*types.Func)
+	scope := pkg.Scope()
+	for _, n := range scope.Names() {
+		f, ok := scope.Lookup(n).(*types.Func)
+		if !ok || !f.Exported() {
+			continue
+		}
+		if isExcluded(filepath.Base(fset.Position(f.Pos()).Filename), excludes) {
+			continue
+		}
+		objs[n] = f
+	}
+	return objs
+}
+
+// returns a list of all exported methods declared on the service type,
+// excluding those declared in the excluded files. methods promoted from
+// its embedded fields (eg. the Lock of a sync.Mutex) aren't served
+func getMethods(fset *gotoken.FileSet, recv types.Type, excludes []string) map[string]*types.Func {
+	objs := make(map[string]*types.Func)
+	mset := types.NewMethodSet(recv)
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		if len(sel.Index()) > 1 {
+			continue
+		}
+		f := sel.Obj().(*types.Func)
+		if !f.Exported() {
+			continue
+		}
+		if isExcluded(filepath.Base(fset.Position(f.Pos()).Filename), excludes) {
+			continue
+		}
+		objs[f.Name()] = f
+	}
+	return objs
+}
+
+// find the service type (eg. *core.Service or Service) in the pkg
+func lookupService(pkg *types.Package, service string) (types.Type, error) {
+	name := strings.TrimPrefix(service, "*")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("service type %s not found in package %s", name, pkg.Path())
+	}
+	var recv types.Type = obj.Type()
+	if strings.HasPrefix(service, "*") {
+		recv = types.NewPointer(recv)
+	}
+	return recv, nil
+}
+
+//--------------------------------------------------------------------------------
+// other parsing utilities
+
+// load and type check the package in dir, with its imports from imp.
+// all of its files are checked, even those excluded from the rpc
+func loadPackage(fset *gotoken.FileSet, imp *srcImporter, dir, importPath string) (*types.Package, []*ast.File, error) {
+	bpkg, err := imp.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []*ast.File{}
+	for _, name := range bpkg.GoFiles {
+		f, err := goparser.ParseFile(fset, filepath.Join(dir, name), nil, goparser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{
+		Importer: imp,
+		// only the signatures matter, so function bodies needn't compile
+		IgnoreFuncBodies: true,
+	}
+	pkg, err := conf.Check(importPath, fset, files, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pkg, files, nil
+}
+
+// check if the file name is in the list of excludes
+func isExcluded(name string, excludes []string) bool {
+	for _, ex := range excludes {
+		if name == ex {
+			return true
+		}
+	}
+	return false
+}
+
+// asserts the map has only one item and returns it
+func onePkg(pkgs map[string]*ast.Package) (*ast.Package, error) {
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package, found %d", len(pkgs))
+	}
+	for _, p := range pkgs {
+		return p, nil
+	}
+	return nil, nil
+}
+
+// return the first function declared after pos in the file
+func funcDeclAfter(file *ast.File, pos gotoken.Pos) *ast.FuncDecl {
+	for _, d := range file.Decls {
+		if fdecl, ok := d.(*ast.FuncDecl); ok && fdecl.Pos() > pos {
+			return fdecl
+		}
+	}
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+// template helpers registered with define-func
+
+// a go function templates can call on the current function's args
+type funcDef struct {
+	name     string
+	params   []string // type of each param, as in source
+	variadic bool     // the last param is ...T (without the ... in params)
+
+	decl *ast.FuncDecl
+	sig  *types.Signature // once resolved, nil if it couldn't be
+}
+
+func newFuncDef(fdecl *ast.FuncDecl) *funcDef {
+	def := &funcDef{name: fdecl.Name.Name, decl: fdecl}
+	for _, field := range fdecl.Type.Params.List {
+		typ := field.Type
+		if ell, ok := typ.(*ast.Ellipsis); ok {
+			def.variadic = true
+			typ = ell.Elt
+		}
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			def.params = append(def.params, types.ExprString(typ))
+		}
+	}
+	return def
+}
+
+// a go expression passed to a helper or set routine
+type operand struct {
+	src    string
+	typ    string     // go type, if known
+	gotype types.Type // the type typ names, for args and return values
+	ref    string     // "args" or "response" if it refers to the function's args or return types
+}
+
+// make a call to the helper with the given operands,
+// converting the function's args to the helper's param types if needed
+func defToCall(def *funcDef, f Func, ops []operand) (string, error) {
+	fixed := len(def.params)
+	if def.variadic {
+		fixed -= 1
+	}
+	if len(ops) < fixed || (!def.variadic && len(ops) > fixed) {
+		return "", fmt.Errorf("%s takes %d arguments, got %d for %s", def.name, len(def.params), len(ops), f.Name)
+	}
+
+	callArgs := make([]string, len(ops))
+	for i, op := range ops {
+		param := def.params[len(def.params)-1]
+		if i < fixed {
+			param = def.params[i]
+		} else if i == len(ops)-1 && op.typ == "..."+param {
+			// pass a variadic arg straight through to the variadic param
+			callArgs[i] = op.src + "..."
+			continue
+		}
+		if err := def.checkConvertible(i, op, param); err != nil {
+			return "", err
+		}
+		callArgs[i] = convertArg(op.src, op.typ, param)
+	}
+	return def.name + "(" + strings.Join(callArgs, ", ") + ")", nil
+}
+
+// check an arg or return value passed to the i'th param can be converted to its
+// type, when both are known. eg. an interface can't be converted to a []byte
+func (def *funcDef) checkConvertible(i int, op operand, paramType string) error {
+	if def.sig == nil || op.gotype == nil {
+		return nil
+	}
+	params := def.sig.Params()
+	var param types.Type
+	if i < params.Len()-1 || !def.variadic {
+		param = params.At(i).Type()
+	} else {
+		param = params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+	}
+	if b, ok := param.(*types.Basic); ok && b.Kind() == types.Invalid {
+		return nil
+	}
+	if !types.ConvertibleTo(op.gotype, param) {
+		return fmt.Errorf("%s: can't convert %s (%s) to %s", def.name, op.src, op.typ, paramType)
+	}
+	return nil
+}
+
+// convert the expression to the param type, unless it already is one
+// or its type isn't known
+func convertArg(name, argType, paramType string) string {
+	if strings.HasPrefix(argType, "...") {
+		argType = "[]" + argType[3:]
+	}
+	switch paramType {
+	case argType, "interface{}", "any":
+		return name
+	}
+	if argType == "" {
+		return name
+	}
+	if strings.HasPrefix(paramType, "*") || strings.HasPrefix(paramType, "func") || strings.HasPrefix(paramType, "<-") {
+		paramType = "(" + paramType + ")"
+	}
+	return paramType + "(" + name + ")"
+}
+
+// resolve a reference to the function's args (args or args.N)
+func resolveArgRef(f Func, ref string) ([]operand, error) {
+	spl := strings.Split(ref, ".")
+	if len(spl) == 1 {
+		ops := make([]operand, len(f.ArgNames))
+		for i, n := range f.ArgNames {
+			ops[i] = operand{src: n, typ: f.ArgTypes[i], gotype: f.params[i], ref: "args"}
+		}
+		return ops, nil
+	}
+	i, err := strconv.Atoi(spl[1])
+	if err != nil || len(spl) > 2 || i < 0 || i >= len(f.ArgNames) {
+		return nil, fmt.Errorf("%s has no argument %s", f.Name, ref)
+	}
+	return []operand{{src: f.ArgNames[i], typ: f.ArgTypes[i], gotype: f.params[i], ref: "args"}}, nil
+}
+
+// resolve a reference to one of the function's return types (response.N)
+func resolveResponseRef(f Func, ref string) (operand, error) {
+	spl := strings.Split(ref, ".")
+	i, err := strconv.Atoi(spl[1])
+	if err != nil || len(spl) > 2 || i < 0 || i >= len(f.ReturnTypes) {
+		return operand{}, fmt.Errorf("%s has no return value %s", f.Name, ref)
+	}
+	typ := f.ReturnTypes[i]
+	return operand{src: typ, typ: typ, gotype: f.results[i], ref: "response"}, nil
+}
+
+//--------------------------------------------------------------------------------
+// serialization routines registered with define-set
+
+// encoders and decoders for particular types.
+// the type _ matches any type without its own entry
+type setDef struct {
+	name     string
+	loc      string            // where it's defined, for errors
+	types    []string          // the types with entries, in order
+	encoders map[string]string // type -> encoder func
+	decoders map[string]string // type -> decoder func
+
+	resolved map[string]types.Type // type -> the type it names, see resolve
+}
+
+// parse the lines of a define-set, each of the form
+//
+//	<type> <encoder> [<decoder>]
+func newSetDef(name, loc string, lines []string) (*setDef, error) {
+	set := &setDef{
+		name:     name,
+		loc:      loc,
+		encoders: make(map[string]string),
+		decoders: make(map[string]string),
+		resolved: make(map[string]types.Type),
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		typ := ""
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			return nil, fmt.Errorf("rpc-gen:define-set %s: expected a type and an encoder, got %q", name, line)
+		case 2:
+			typ = fields[0]
+			set.encoders[typ] = fields[1]
+		default:
+			// the type may have spaces in it
+			n := len(fields)
+			typ = strings.Join(fields[:n-2], " ")
+			set.encoders[typ] = fields[n-2]
+			set.decoders[typ] = fields[n-1]
+		}
+		if typ != "_" {
+			set.types = append(set.types, typ)
+		}
+	}
+	return set, nil
+}
+
+// resolve the set's types, as they'd be written in the package being generated:
+// imports are the packages by the names in imports, eg. the rpc-gen:imports and
+// those of the core package's files. they're matched to the args by identity,
+// so []uint8 is []byte, however the generated code ends up naming the package
+func (set *setDef) resolve(fset *gotoken.FileSet, imp *srcImporter, imports map[string]string) error {
+	if len(set.types) == 0 {
+		return nil
+	}
+	// only the packages the types name are imported
+	used := make(map[string]bool)
+	for _, typ := range set.types {
+		expr, err := goparser.ParseExpr(typ)
+		if err != nil {
+			return fmt.Errorf("%s: rpc-gen:define-set %s: invalid type %s", set.loc, set.name, typ)
+		}
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					used[x.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	names := []string{}
+	for n := range imports {
+		if used[n] {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	src := new(bytes.Buffer)
+	src.WriteString("package set\n\nimport (\n")
+	for _, n := range names {
+		fmt.Fprintf(src, "\t%s %q\n", n, imports[n])
+	}
+	src.WriteString(")\n\nvar (\n")
+	for _, typ := range set.types {
+		fmt.Fprintf(src, "\t_ %s\n", typ)
+	}
+	src.WriteString(")\n")
+	file, err := goparser.ParseFile(fset, "", src.Bytes(), 0)
+	if err != nil {
+		return fmt.Errorf("%s: rpc-gen:define-set %s: invalid types", set.loc, set.name)
+	}
+
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	var typeErr error
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			if typeErr == nil {
+				typeErr = err
+			}
+		},
+	}
+	conf.Check("set", fset, []*ast.File{file}, info)
+	if typeErr != nil {
+		if terr, ok := typeErr.(types.Error); ok {
+			typeErr = errors.New(terr.Msg)
+		}
+		return fmt.Errorf("%s: rpc-gen:define-set %s: %v", set.loc, set.name, typeErr)
+	}
+	specs := file.Decls[len(file.Decls)-1].(*ast.GenDecl).Specs
+	for i, typ := range set.types {
+		t := info.TypeOf(specs[i].(*ast.ValueSpec).Type)
+		for _, other := range set.types[:i] {
+			if types.Identical(t, set.resolved[other]) {
+				return fmt.Errorf("%s: rpc-gen:define-set %s: %s and %s are the same type", set.loc, set.name, other, typ)
+			}
+		}
+		set.resolved[typ] = t
+	}
+	return nil
+}
+
+// the scope set types are resolved in: the template imports, the core package,
+// then the packages the core package's files import, by the names they use
+func setImports(templateImports map[string]string, coreName string, core *types.Package, coreFiles []*ast.File) map[string]string {
+	imports := map[string]string{coreName: core.Path()}
+	for n, p := range templateImports {
+		imports[n] = p
+	}
+	pkgNames := make(map[string]string)
+	for _, p := range core.Imports() {
+		pkgNames[p.Path()] = p.Name()
+	}
+	for _, file := range coreFiles {
+		for _, spec := range file.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			n := pkgNames[p]
+			if spec.Name != nil {
+				n = spec.Name.Name
+			}
+			if _, taken := imports[n]; !taken && n != "" && n != "_" && n != "." {
+				imports[n] = p
+			}
+		}
+	}
+	return imports
+}
+
+// find the routine for the type of the operand, falling back to the default
+func (set *setDef) lookup(routines map[string]string, op operand) (string, bool) {
+	if op.gotype != nil {
+		for _, typ := range set.types {
+			if types.Identical(set.resolved[typ], op.gotype) {
+				if r, ok := routines[typ]; ok {
+					return r, true
+				}
+				break
+			}
+		}
+	}
+	r, ok := routines["_"]
+	return r, ok
+}
+
+// apply the set's encoders to each of the function's args among the operands,
+// returning a comma separated list of calls. args with no encoder are left as they are.
+// any other operands (eg. string literals) are passed to each encoder as options
+func setToEncodeCalls(set *setDef, ops []operand) (string, error) {
+	opts := ""
+	for _, op := range ops {
+		if op.ref != "args" {
+			opts += ", " + op.src
+		}
+	}
+	calls := []string{}
+	for _, op := range ops {
+		if op.ref != "args" {
+			continue
+		}
+		call := op.src
+		if enc, ok := set.lookup(set.encoders, op); ok {
+			call = enc + "(" + op.src + opts + ")"
+		}
+		calls = append(calls, call)
+	}
+	return strings.Join(calls, ", "), nil
+}
+
+// apply the decoder for the type of the referred arg or response to the go expression.
+// any further operands are passed to the decoder as options
+func setToDecodeCall(set *setDef, ops []operand) (string, error) {
+	if len(ops) < 2 {
+		return "", fmt.Errorf("%s.decode expects a type reference and an expression, got %d args", set.name, len(ops))
+	}
+	if ops[0].ref == "" {
+		return "", fmt.Errorf("%s.decode expects a reference to an arg or return value, got %s", set.name, ops[0].src)
+	}
+	dec, ok := set.lookup(set.decoders, ops[0])
+	if !ok {
+		return "", fmt.Errorf("%s has no decoder for %s", set.name, ops[0].typ)
+	}
+	args := []string{}
+	for _, op := range ops[1:] {
+		args = append(args, op.src)
+	}
+	return dec + "(" + strings.Join(args, ", ") + ")", nil
+}